@@ -0,0 +1,174 @@
+package ch
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultAnchorCapacity is the number of buckets used when none is provided
+const DefaultAnchorCapacity = 1024
+
+// Anchor represents AnchorHash of Mendelson et al. Nodes are bound to
+// buckets of a fixed capacity: a key hashes to a bucket, and when that
+// bucket is unused it is rehashed into the buckets that were still in use
+// when it was removed, until it lands on a working one. Keys only ever move
+// to or from the node that changed, and spread evenly over the remaining
+// nodes after any sequence of removals, in O(1) memory per bucket and O(1)
+// expected lookups.
+//
+// The binding of nodes to buckets follows the order of the membership
+// changes, so processes must apply the same changes in the same order to
+// route keys the same way. Removing the last added node restores the
+// previous mapping exactly.
+type Anchor struct {
+	mu      sync.RWMutex
+	hash    Hash64
+	size    uint32
+	working uint32   // Number of nodes, N in the paper
+	a       []uint32 // Bucket -> Working set size right after its removal, 0 for working buckets
+	w       []uint32 // Working set: position -> Bucket
+	l       []uint32 // Bucket -> Position in w
+	k       []uint32 // Bucket -> Successor replacing it in w when removed
+	removed []uint32 // Stack of removed buckets, R in the paper
+
+	buckets map[string]uint32 // Node -> Bucket
+	nodes   []string          // Bucket -> Node, empty for removed buckets
+}
+
+// NewAnchor creates a new AnchorHash instance with room for capacity nodes,
+// DefaultAnchorCapacity when zero, hashing keys with fn, XXHash64 when nil.
+// The memory used does not depend on the number of nodes.
+func NewAnchor(capacity int, fn Hash64) (*Anchor, error) {
+	if capacity == 0 {
+		capacity = DefaultAnchorCapacity
+	}
+	if capacity < 0 || uint64(capacity) > 1<<32-1 {
+		return nil, ErrInvalidCapacity
+	}
+	if fn == nil {
+		fn = XXHash64
+	}
+
+	size := uint32(capacity)
+	ah := &Anchor{
+		hash:    fn,
+		size:    size,
+		a:       make([]uint32, size),
+		w:       make([]uint32, size),
+		l:       make([]uint32, size),
+		k:       make([]uint32, size),
+		removed: make([]uint32, 0, size),
+		buckets: make(map[string]uint32),
+		nodes:   make([]string, size),
+	}
+	// Every bucket starts removed, the highest first, so nodes are bound to
+	// buckets 0, 1, 2... as they are added
+	for b := size; b > 0; b-- {
+		bucket := b - 1
+		ah.a[bucket] = bucket
+		ah.w[bucket] = bucket
+		ah.l[bucket] = bucket
+		ah.k[bucket] = bucket
+		ah.removed = append(ah.removed, bucket)
+	}
+	return ah, nil
+}
+
+// AddNode binds a node to the last removed bucket, ErrCapacityExceeded when
+// every bucket is in use
+func (ah *Anchor) AddNode(node string) error {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+
+	if _, ok := ah.buckets[node]; ok {
+		return ErrNodeExists
+	}
+	if len(ah.removed) == 0 {
+		return ErrCapacityExceeded
+	}
+
+	b := ah.removed[len(ah.removed)-1]
+	ah.removed = ah.removed[:len(ah.removed)-1]
+	ah.a[b] = 0
+	ah.l[ah.w[ah.working]] = ah.working
+	ah.w[ah.l[b]] = b
+	ah.k[b] = b
+	ah.working++
+
+	ah.buckets[node] = b
+	ah.nodes[b] = node
+	return nil
+}
+
+// RemoveNode removes a node and frees its bucket
+func (ah *Anchor) RemoveNode(node string) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+
+	b, ok := ah.buckets[node]
+	if !ok {
+		return
+	}
+	delete(ah.buckets, node)
+	ah.nodes[b] = ""
+
+	// The last working bucket takes the place of b in the working set
+	ah.removed = append(ah.removed, b)
+	ah.working--
+	ah.a[b] = ah.working
+	last := ah.w[ah.working]
+	ah.w[ah.l[b]] = last
+	ah.l[last] = ah.l[b]
+	ah.k[b] = last
+}
+
+// Nodes returns the nodes sorted by name
+func (ah *Anchor) Nodes() []string {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+
+	nodes := make([]string, 0, len(ah.buckets))
+	for node := range ah.buckets {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Capacity returns the number of buckets, the most nodes the instance holds
+func (ah *Anchor) Capacity() int {
+	return int(ah.size)
+}
+
+// GetNode returns the node owning the provided key
+func (ah *Anchor) GetNode(key string) string {
+	return ah.GetNodeBytes([]byte(key))
+}
+
+// GetNodeBytes returns the node owning a key given as bytes
+func (ah *Anchor) GetNodeBytes(key []byte) string {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+
+	if ah.working == 0 {
+		return ""
+	}
+	return ah.nodes[ah.bucket(ah.hash(key))]
+}
+
+// bucket returns the working bucket of a key hash. A removed bucket b
+// rehashes the key into the A[b] buckets that were working when b was
+// removed; a bucket h of those that was removed earlier was replaced in the
+// working set by K[h], whose chain leads to a bucket working at the time.
+// Callers must hold the lock.
+func (ah *Anchor) bucket(hash uint64) uint32 {
+	b := uint32(hash % uint64(ah.size))
+	for ah.a[b] > 0 {
+		h := uint32(fmix64(hash^uint64(b)*0x9e3779b97f4a7c15) % uint64(ah.a[b]))
+		for ah.a[h] >= ah.a[b] {
+			h = ah.k[h]
+		}
+		b = h
+	}
+	return b
+}
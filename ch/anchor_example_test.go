@@ -0,0 +1,21 @@
+package ch
+
+import (
+	"fmt"
+	"log"
+)
+
+func ExampleNewAnchor() {
+	ah, err := NewAnchor(DefaultAnchorCapacity, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ah.AddNode("10.0.0.1:80")
+	ah.AddNode("10.0.0.2:80")
+	ah.AddNode("10.0.0.3:80")
+
+	// The keys of the removed node spread evenly over the two others
+	ah.RemoveNode("10.0.0.2:80")
+	fmt.Println("Backend:", ah.GetNode("198.51.100.7:51234"))
+}
@@ -0,0 +1,195 @@
+package ch
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestAnchor_InvalidCapacity(t *testing.T) {
+	if _, err := NewAnchor(-1, nil); err != ErrInvalidCapacity {
+		t.Errorf("Expected %v, got %v", ErrInvalidCapacity, err)
+	}
+
+	ah, err := NewAnchor(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ah.Capacity() != DefaultAnchorCapacity {
+		t.Errorf("Expected default capacity, got %d", ah.Capacity())
+	}
+}
+
+func TestAnchor_CapacityExceeded(t *testing.T) {
+	ah, _ := NewAnchor(3, nil)
+	for i := 0; i < 3; i++ {
+		if err := ah.AddNode("Node" + strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ah.AddNode("Node3"); err != ErrCapacityExceeded {
+		t.Errorf("Expected %v, got %v", ErrCapacityExceeded, err)
+	}
+
+	// A removal frees a bucket for another node
+	ah.RemoveNode("Node1")
+	if err := ah.AddNode("Node3"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if node := ah.GetNode("key" + strconv.Itoa(i)); node == "Node1" || node == "" {
+			t.Fatalf("Expected a working node, got %q", node)
+		}
+	}
+}
+
+func TestAnchor_EmptyAfterRemovals(t *testing.T) {
+	ah, _ := NewAnchor(8, nil)
+	ah.AddNode("NodeA")
+	ah.AddNode("NodeB")
+	ah.RemoveNode("NodeA")
+	ah.RemoveNode("NodeB")
+	if node := ah.GetNode("key"); node != "" {
+		t.Errorf("Expected empty string without nodes, got %s", node)
+	}
+
+	ah.AddNode("NodeC")
+	if node := ah.GetNode("key"); node != "NodeC" {
+		t.Errorf("Expected NodeC, got %s", node)
+	}
+}
+
+// ownerCounts returns the owner of every key and how many keys each node owns
+func ownerCounts(r Ring, keys int) ([]string, map[string]int) {
+	owners := make([]string, keys)
+	counts := make(map[string]int)
+	for i := range owners {
+		owners[i] = r.GetNode("key" + strconv.Itoa(i))
+		counts[owners[i]]++
+	}
+	return owners, counts
+}
+
+func peakToMean(counts map[string]int, keys int) float64 {
+	peak := 0
+	for _, c := range counts {
+		if c > peak {
+			peak = c
+		}
+	}
+	return float64(peak) * float64(len(counts)) / float64(keys)
+}
+
+// TestAnchor_ArbitraryRemovals removes a random half of the nodes and checks
+// that only their keys move and that the survivors stay as balanced as on a
+// fresh instance, unlike a Map whose survivors inherit uneven arcs
+func TestAnchor_ArbitraryRemovals(t *testing.T) {
+	const nodes, keys = 40, 200000
+
+	ah, _ := NewAnchor(DefaultAnchorCapacity, nil)
+	m := New64[string](100, nil)
+	for i := 0; i < nodes; i++ {
+		ah.AddNode("Node" + strconv.Itoa(i))
+		m.AddNode("Node" + strconv.Itoa(i))
+	}
+	anchorBefore, _ := ownerCounts(ah, keys)
+	mapBefore, _ := ownerCounts(m, keys)
+
+	removed := make(map[string]bool)
+	for _, i := range rand.New(rand.NewSource(1)).Perm(nodes)[:nodes/2] {
+		node := "Node" + strconv.Itoa(i)
+		removed[node] = true
+		ah.RemoveNode(node)
+		m.RemoveNode(node)
+	}
+	anchorAfter, anchorCounts := ownerCounts(ah, keys)
+	mapAfter, mapCounts := ownerCounts(m, keys)
+
+	moved := func(before, after []string) (int, int) {
+		total, stray := 0, 0
+		for i := range before {
+			if removed[after[i]] {
+				t.Fatalf("Key %d still mapped to the removed %s", i, after[i])
+			}
+			if before[i] != after[i] {
+				total++
+				if !removed[before[i]] {
+					stray++
+				}
+			}
+		}
+		return total, stray
+	}
+
+	anchorMoved, anchorStray := moved(anchorBefore, anchorAfter)
+	mapMoved, mapStray := moved(mapBefore, mapAfter)
+	if anchorStray != 0 || mapStray != 0 {
+		t.Errorf("Expected only keys of removed nodes to move, got %d and %d strays", anchorStray, mapStray)
+	}
+	t.Logf("Moved keys: Anchor %d, Map %d", anchorMoved, mapMoved)
+
+	if len(anchorCounts) != nodes/2 {
+		t.Fatalf("Expected keys on %d nodes, got %d", nodes/2, len(anchorCounts))
+	}
+	anchorPeak, mapPeak := peakToMean(anchorCounts, keys), peakToMean(mapCounts, keys)
+	if anchorPeak > 1.05 {
+		t.Errorf("Expected a peak-to-mean load under 1.05 after removals, got %.3f", anchorPeak)
+	}
+	if anchorPeak > mapPeak {
+		t.Errorf("Expected a better balance than Map, got %.3f and %.3f", anchorPeak, mapPeak)
+	}
+}
+
+func TestAnchor_Readd(t *testing.T) {
+	const keys = 20000
+
+	ah, _ := NewAnchor(64, nil)
+	for i := 0; i < 10; i++ {
+		ah.AddNode("Node" + strconv.Itoa(i))
+	}
+	before, _ := ownerCounts(ah, keys)
+
+	// Adding back in the reverse order of removal restores every key
+	ah.RemoveNode("Node3")
+	ah.RemoveNode("Node7")
+	ah.AddNode("Node7")
+	ah.AddNode("Node3")
+	after, _ := ownerCounts(ah, keys)
+	for i := range before {
+		if before[i] != after[i] {
+			t.Fatalf("Key %d moved from %s to %s", i, before[i], after[i])
+		}
+	}
+
+	// Any node added after a removal only takes keys, from every node
+	ah.RemoveNode("Node5")
+	removed, _ := ownerCounts(ah, keys)
+	ah.AddNode("Extra")
+	added, counts := ownerCounts(ah, keys)
+	for i := range removed {
+		if added[i] != removed[i] && added[i] != "Extra" {
+			t.Fatalf("Key %d moved from %s to %s", i, removed[i], added[i])
+		}
+	}
+	if peak := peakToMean(counts, keys); peak > 1.1 {
+		t.Errorf("Expected a peak-to-mean load under 1.1, got %.3f", peak)
+	}
+}
+
+func BenchmarkAnchor_GetNode(b *testing.B) {
+	ah, _ := NewAnchor(DefaultAnchorCapacity, nil)
+	for i := 0; i < 100; i++ {
+		ah.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	keys := make([][]byte, 1024)
+	for i := range keys {
+		keys[i] = []byte("key" + strconv.Itoa(i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ah.GetNodeBytes(keys[i%len(keys)])
+	}
+}
@@ -0,0 +1,91 @@
+package ch
+
+import "sort"
+
+// AddNodes adds nodes with weight 1 to the hash ring in a single update.
+// The new virtual nodes are merged with the ring in one pass, which makes
+// bootstrapping a large ring linear instead of quadratic. Nothing is added
+// when a node is already on the ring or listed twice.
+func (m *Map[T]) AddNodes(nodes ...string) error {
+	set := make([]nodeConfig, len(nodes))
+	seen := make(map[string]struct{}, len(nodes))
+	for i, node := range nodes {
+		if _, ok := seen[node]; ok {
+			return ErrNodeExists
+		}
+		seen[node] = struct{}{}
+		set[i] = nodeConfig{Name: node, Weight: 1}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, node := range nodes {
+		if _, ok := m.weights[node]; ok {
+			return ErrNodeExists
+		}
+	}
+
+	before := m.watch()
+	events, _ := m.change(nil, set)
+	m.publish(before, events)
+	return nil
+}
+
+// RemoveNodes removes nodes from the hash ring in a single update, ignoring
+// the nodes that are not on it. The eviction hook, if any, sees the
+// partition of every removed node in turn.
+func (m *Map[T]) RemoveNodes(nodes ...string) {
+	m.mu.Lock()
+	before := m.watch()
+	events, evicted := m.change(nodes, nil)
+	m.publish(before, events)
+	hook := m.evictionHook
+	m.mu.Unlock()
+
+	if hook == nil {
+		return
+	}
+	for _, e := range events {
+		hook(e.Node, evicted[e.Node])
+	}
+}
+
+// SetNodes makes the ring membership match the nodes and weights given:
+// missing nodes are added, nodes not listed are removed and the others move
+// to their new weight. The whole diff is applied under a single lock, so
+// readers see either the old ring or the new one, never a mix.
+func (m *Map[T]) SetNodes(weights map[string]int) error {
+	set := make([]nodeConfig, 0, len(weights))
+	for node, weight := range weights {
+		if weight < 1 {
+			return ErrInvalidWeight
+		}
+		set = append(set, nodeConfig{Name: node, Weight: weight})
+	}
+	sort.Slice(set, func(i, j int) bool {
+		return set[i].Name < set[j].Name
+	})
+
+	m.mu.Lock()
+	var remove []string
+	for node := range m.weights {
+		if _, ok := weights[node]; !ok {
+			remove = append(remove, node)
+		}
+	}
+	sort.Strings(remove)
+
+	before := m.watch()
+	events, evicted := m.change(remove, set)
+	m.publish(before, events)
+	hook := m.evictionHook
+	m.mu.Unlock()
+
+	if hook != nil {
+		for _, node := range remove {
+			hook(node, evicted[node])
+		}
+	}
+	return nil
+}
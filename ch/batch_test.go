@@ -0,0 +1,181 @@
+package ch
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestBatch_AddNodesMatchesSequential(t *testing.T) {
+	for _, fn := range []Hash{nil, collidingHash} {
+		nodes := make([]string, 20)
+		sequential := New[string](50, fn)
+		for i := range nodes {
+			nodes[i] = "Node" + strconv.Itoa(i)
+			_ = sequential.AddNode(nodes[i])
+		}
+
+		batch := New[string](50, fn)
+		if err := batch.AddNodes(nodes...); err != nil {
+			t.Fatal(err)
+		}
+		checkRing(t, batch)
+		checkSameRing(t, sequential, batch)
+	}
+}
+
+func TestBatch_AddNodesErrors(t *testing.T) {
+	ch := New[string](10, nil)
+	_ = ch.AddNode("NodeA")
+
+	if err := ch.AddNodes("NodeB", "NodeA"); err != ErrNodeExists {
+		t.Errorf("Expected %v, got %v", ErrNodeExists, err)
+	}
+	if err := ch.AddNodes("NodeB", "NodeC", "NodeB"); err != ErrNodeExists {
+		t.Errorf("Expected %v, got %v", ErrNodeExists, err)
+	}
+	if nodes := ch.Nodes(); len(nodes) != 1 {
+		t.Errorf("Expected a failed batch to add nothing, got %v", nodes)
+	}
+	if err := ch.AddNodes(); err != nil {
+		t.Errorf("Expected an empty batch to succeed, got %v", err)
+	}
+}
+
+func TestBatch_RemoveNodes(t *testing.T) {
+	sequential := New[int](30, collidingHash)
+	batch := New[int](30, collidingHash)
+	for _, ch := range []*Map[int]{sequential, batch} {
+		_ = ch.AddNodes("NodeA", "NodeB", "NodeC", "NodeD")
+		for i := 0; i < 200; i++ {
+			ch.AddKey("key"+strconv.Itoa(i), i)
+		}
+	}
+	owned := batch.KeyCount("NodeB") + batch.KeyCount("NodeD")
+
+	evicted := make(map[string]int)
+	batch.SetEvictionHook(func(node string, data map[string]int) {
+		evicted[node] = len(data)
+	})
+
+	sequential.RemoveNode("NodeB")
+	sequential.RemoveNode("NodeD")
+	batch.RemoveNodes("NodeB", "Missing", "NodeD", "NodeB")
+	checkRing(t, batch)
+	checkSameRing(t, sequential, batch)
+	checkPartitions(t, batch)
+
+	if len(evicted) != 2 || evicted["NodeB"]+evicted["NodeD"] != owned {
+		t.Errorf("Expected %d evicted keys of NodeB and NodeD, got %v", owned, evicted)
+	}
+}
+
+func TestBatch_SetNodes(t *testing.T) {
+	for _, fn := range []Hash{nil, collidingHash} {
+		ch := New[int](20, fn)
+		_ = ch.AddNodes("NodeA", "NodeB", "NodeC")
+		_ = ch.SetWeight("NodeC", 3)
+		for i := 0; i < 200; i++ {
+			ch.AddKey("key"+strconv.Itoa(i), i)
+		}
+
+		want := map[string]int{"NodeA": 1, "NodeC": 1, "NodeD": 2, "NodeE": 1}
+		if err := ch.SetNodes(want); err != nil {
+			t.Fatal(err)
+		}
+		checkRing(t, ch)
+		checkPartitions(t, ch)
+
+		fresh := New[int](20, fn)
+		for node, weight := range want {
+			_ = fresh.AddNodeWithWeight(node, weight)
+		}
+		checkSameRing(t, fresh, ch)
+		for node, weight := range want {
+			if ch.Weight(node) != weight {
+				t.Errorf("Expected %s to weigh %d, got %d", node, weight, ch.Weight(node))
+			}
+		}
+		if ch.Weight("NodeB") != 0 {
+			t.Errorf("Expected NodeB to be removed")
+		}
+	}
+}
+
+func TestBatch_SetNodesInvalidWeight(t *testing.T) {
+	ch := New[string](10, nil)
+	_ = ch.AddNodes("NodeA", "NodeB")
+	fingerprint := ch.Fingerprint()
+
+	if err := ch.SetNodes(map[string]int{"NodeA": 1, "NodeC": 0}); err != ErrInvalidWeight {
+		t.Errorf("Expected %v, got %v", ErrInvalidWeight, err)
+	}
+	if ch.Fingerprint() != fingerprint || len(ch.Nodes()) != 2 {
+		t.Errorf("Expected a failed batch to leave the ring unchanged")
+	}
+}
+
+func TestBatch_SetNodesEvents(t *testing.T) {
+	ch := New[string](20, nil)
+	_ = ch.AddNodes("NodeA", "NodeB", "NodeC")
+
+	keys := 5000
+	before := make([]string, keys)
+	for i := range before {
+		before[i] = ch.GetNode("key" + strconv.Itoa(i))
+	}
+
+	events, cancel := ch.Subscribe()
+	defer cancel()
+	_ = ch.SetNodes(map[string]int{"NodeA": 2, "NodeC": 1, "NodeD": 1})
+
+	got := make(map[string]Event)
+	for i := 0; i < 3; i++ {
+		e := nextEvent(t, events)
+		got[e.Node] = e
+	}
+	want := map[string]EventType{"NodeA": EventWeightChanged, "NodeB": EventNodeRemoved, "NodeD": EventNodeAdded}
+	for node, typ := range want {
+		if got[node].Type != typ {
+			t.Errorf("Expected %v for %s, got %v", typ, node, got[node].Type)
+		}
+	}
+
+	// Every moved key is in the ranges of the nodes it moved between
+	for i := range before {
+		key := "key" + strconv.Itoa(i)
+		after := ch.GetNode(key)
+		if after == before[i] {
+			continue
+		}
+		hash := ch.hash([]byte(key))
+		for _, node := range []string{before[i], after} {
+			if e, ok := got[node]; ok && !inRanges(hash, e.Ranges) {
+				t.Fatalf("Key %s moved from %s to %s outside the ranges of %s", key, before[i], after, node)
+			}
+		}
+	}
+}
+
+func BenchmarkBatch_AddNodes(b *testing.B) {
+	nodes := make([]string, 2000)
+	for i := range nodes {
+		nodes[i] = "Node" + strconv.Itoa(i)
+	}
+
+	b.Run("Batch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ch := New[string](200, nil)
+			_ = ch.AddNodes(nodes...)
+		}
+	})
+	b.Run("Sequential", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ch := New[string](200, nil)
+			for _, node := range nodes {
+				_ = ch.AddNode(node)
+			}
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package ch
+
+import "math"
+
+// SetLoadFactor enables consistent hashing with bounded loads (Mirrokni et al.).
+// Each node may hold at most ceil(c * averageLoad) acquired keys, scaled by its
+// share of the total weight, and lookups walk clockwise past nodes that are full.
+// A factor of zero disables the bound.
+func (m *Map[T]) SetLoadFactor(c float64) error {
+	if c != 0 && (c < 1 || math.IsNaN(c) || math.IsInf(c, 0)) {
+		return ErrInvalidLoadFactor
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loadFactor = c
+	m.bounded.Store(c != 0)
+	return nil
+}
+
+// LoadFactor returns the bounded-load factor, zero when the bound is disabled
+func (m *Map[T]) LoadFactor() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.loadFactor
+}
+
+// Acquire picks the node for the provided key and adds one unit of load to it.
+// Draining and down nodes take no new load. Every successful Acquire must be
+// paired with a Release of the returned node.
+func (m *Map[T]) Acquire(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node := m.lookup(m.hash([]byte(key)), StateDraining)
+	if node == "" {
+		return "", ErrNoNodes
+	}
+	m.loads[node]++
+	m.totalLoad++
+	return node, nil
+}
+
+// Release removes one unit of load from the node
+func (m *Map[T]) Release(node string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.loads[node] > 0 {
+		m.loads[node]--
+		m.totalLoad--
+	}
+}
+
+// Loads returns the current acquired load of every node
+func (m *Map[T]) Loads() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	loads := make(map[string]int, len(m.loads))
+	for node, load := range m.loads {
+		loads[node] = load
+	}
+	return loads
+}
+
+// capacity returns the maximum load a node may carry before lookups skip it.
+// The bound is proportional to the node weight, and the pending request is
+// counted so that at least one node always has room. Callers must hold the lock.
+func (m *Map[T]) capacity(node string) int {
+	if m.totalWeight == 0 {
+		return 0
+	}
+	share := float64(m.weights[node]) / float64(m.totalWeight)
+	return int(math.Ceil(m.loadFactor * float64(m.totalLoad+1) * share))
+}
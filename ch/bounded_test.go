@@ -0,0 +1,141 @@
+package ch
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestBoundedLoad_SetLoadFactor(t *testing.T) {
+	tests := []struct {
+		name      string
+		factor    float64
+		expectErr error
+	}{
+		{"Disabled", 0, nil},
+		{"Exact", 1, nil},
+		{"Epsilon", 1.25, nil},
+		{"Below one", 0.5, ErrInvalidLoadFactor},
+		{"Negative", -1, ErrInvalidLoadFactor},
+		{"NaN", math.NaN(), ErrInvalidLoadFactor},
+		{"Infinity", math.Inf(1), ErrInvalidLoadFactor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := New[string](3, nil)
+			if err := ch.SetLoadFactor(tt.factor); err != tt.expectErr {
+				t.Errorf("Expected error %v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestBoundedLoad_AcquireEmptyRing(t *testing.T) {
+	ch := New[string](3, nil)
+	if _, err := ch.Acquire("key"); err != ErrNoNodes {
+		t.Errorf("Expected %v, got %v", ErrNoNodes, err)
+	}
+}
+
+func TestBoundedLoad_HotKeyIsSpread(t *testing.T) {
+	ch := New[string](50, nil)
+	for i := 0; i < 4; i++ {
+		ch.AddNode("Node" + strconv.Itoa(i))
+	}
+	if err := ch.SetLoadFactor(1.25); err != nil {
+		t.Fatal(err)
+	}
+
+	primary := ch.GetNode("hot-key")
+	acquired := 100
+	for i := 0; i < acquired; i++ {
+		if _, err := ch.Acquire("hot-key"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	capacity := int(math.Ceil(1.25 * float64(acquired) / 4))
+	total := 0
+	for node, load := range ch.Loads() {
+		if load > capacity {
+			t.Errorf("Node %s holds %d, above capacity %d", node, load, capacity)
+		}
+		total += load
+	}
+	if total != acquired {
+		t.Errorf("Expected total load %d, got %d", acquired, total)
+	}
+	if ch.Loads()[primary] != capacity {
+		t.Errorf("Expected primary %s to be filled to %d, got %d", primary, capacity, ch.Loads()[primary])
+	}
+}
+
+func TestBoundedLoad_Release(t *testing.T) {
+	ch := New[string](10, nil)
+	ch.AddNode("NodeA")
+	ch.AddNode("NodeB")
+	_ = ch.SetLoadFactor(1)
+
+	first, _ := ch.Acquire("key")
+	second, _ := ch.Acquire("key")
+	if first == second {
+		t.Fatalf("Expected the second acquire to skip the full node %s", first)
+	}
+
+	ch.Release(first)
+	if got := ch.GetNode("key"); got != first {
+		t.Errorf("Expected %s after release, got %s", first, got)
+	}
+
+	ch.Release(first)
+	if ch.Loads()[first] != 0 {
+		t.Errorf("Expected load to stay at zero, got %d", ch.Loads()[first])
+	}
+}
+
+func TestBoundedLoad_DisabledKeepsRingOwner(t *testing.T) {
+	ch := New[string](10, nil)
+	ch.AddNode("NodeA")
+	ch.AddNode("NodeB")
+
+	owner := ch.GetNode("key")
+	for i := 0; i < 10; i++ {
+		node, _ := ch.Acquire("key")
+		if node != owner {
+			t.Fatalf("Expected %s without a load bound, got %s", owner, node)
+		}
+	}
+}
+
+func TestBoundedLoad_RemoveNodeDropsLoad(t *testing.T) {
+	ch := New[string](10, nil)
+	ch.AddNode("NodeA")
+	ch.AddNode("NodeB")
+	_ = ch.SetLoadFactor(1.5)
+
+	node, _ := ch.Acquire("key")
+	ch.RemoveNode(node)
+
+	if _, ok := ch.Loads()[node]; ok {
+		t.Errorf("Expected load of removed node %s to be dropped", node)
+	}
+	if ch.totalLoad != 0 {
+		t.Errorf("Expected total load 0, got %d", ch.totalLoad)
+	}
+}
+
+func BenchmarkBoundedLoad_Acquire(b *testing.B) {
+	ch := New[string](100, nil)
+	for i := 0; i < 100; i++ {
+		ch.AddNode("Node" + strconv.Itoa(i))
+	}
+	_ = ch.SetLoadFactor(1.25)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		node, _ := ch.Acquire("key" + strconv.Itoa(i))
+		ch.Release(node)
+	}
+}
@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Ja7ad/algo/ch"
+)
+
+// Defaults of a Cache
+const (
+	DefaultBasePath   = "/_cache/"
+	DefaultReplicas   = 100
+	DefaultCacheBytes = 64 << 20
+)
+
+// Getter loads the value of a key missing from the cache, usually from the
+// source of truth. It only runs on the peer owning the key.
+type Getter func(ctx context.Context, key string) ([]byte, error)
+
+// Config configures a Cache
+type Config struct {
+	// Self is the base URL other peers reach this one at, e.g.
+	// "http://10.0.0.1:8080"
+	Self string
+	// BasePath is the path the peers serve keys under, DefaultBasePath when
+	// empty
+	BasePath string
+	// Replicas is the number of virtual nodes per peer, DefaultReplicas when
+	// zero
+	Replicas int
+	// CacheBytes bounds the keys and values this peer owns,
+	// DefaultCacheBytes when zero
+	CacheBytes int64
+	// HotCacheBytes bounds the copies of keys owned by other peers, an eighth
+	// of CacheBytes when zero
+	HotCacheBytes int64
+	// Client fetches keys from the other peers, http.DefaultClient when nil
+	Client *http.Client
+}
+
+// Cache is a distributed read-through cache. Every peer owns a slice of the
+// keyspace on a consistent hash ring: a Get for a key owned by another peer
+// is fetched from it over HTTP, and only the owner calls the getter on a
+// miss, so each value is loaded once for the whole cluster. Values are
+// immutable: there is no update or invalidation of a cached key.
+type Cache struct {
+	self     string
+	basePath string
+	client   *http.Client
+	getter   Getter
+	ring     *ch.Map[struct{}]
+	main     *lru // Keys owned by this peer
+	hot      *lru // Copies of keys owned by other peers
+	flight   flight
+	stats    stats
+}
+
+// Stats counts the operations of a Cache since it was created
+type Stats struct {
+	Gets         int64 // Calls to Get
+	Hits         int64 // Gets served from the cache of owned keys
+	HotHits      int64 // Gets served from the hot cache
+	Loads        int64 // Calls to the getter
+	PeerLoads    int64 // Keys fetched from another peer
+	PeerErrors   int64 // Unreachable owners, loaded locally instead
+	Deduplicated int64 // Gets that waited for a load of the same key
+	ServerGets   int64 // Keys requested by other peers
+}
+
+type stats struct {
+	gets, hits, hotHits, loads, peerLoads, peerErrors, deduplicated, serverGets atomic.Int64
+}
+
+// New creates the cache of a peer. The peer serves the keys it owns to the
+// others through ServeHTTP, which must be mounted under BasePath.
+func New(cfg Config, getter Getter) (*Cache, error) {
+	if getter == nil {
+		return nil, ErrNoGetter
+	}
+	self, err := peerURL(cfg.Self)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.BasePath == "" {
+		cfg.BasePath = DefaultBasePath
+	}
+	if cfg.Replicas == 0 {
+		cfg.Replicas = DefaultReplicas
+	}
+	if cfg.CacheBytes == 0 {
+		cfg.CacheBytes = DefaultCacheBytes
+	}
+	if cfg.HotCacheBytes == 0 {
+		cfg.HotCacheBytes = cfg.CacheBytes / 8
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+
+	c := &Cache{
+		self:     self,
+		basePath: cfg.BasePath,
+		client:   cfg.Client,
+		getter:   getter,
+		ring:     ch.New64[struct{}](cfg.Replicas, nil),
+		main:     newLRU(cfg.CacheBytes),
+		hot:      newLRU(cfg.HotCacheBytes),
+	}
+	_ = c.ring.AddNode(self)
+	return c, nil
+}
+
+// SetPeers replaces the peers sharing the keyspace, given as base URLs. This
+// peer always stays on the ring, whether it is listed or not.
+func (c *Cache) SetPeers(peers ...string) error {
+	weights := map[string]int{c.self: 1}
+	for _, peer := range peers {
+		u, err := peerURL(peer)
+		if err != nil {
+			return err
+		}
+		weights[u] = 1
+	}
+	return c.ring.SetNodes(weights)
+}
+
+// Peers returns the base URLs of the peers, sorted
+func (c *Cache) Peers() []string {
+	return c.ring.Nodes()
+}
+
+// Owner returns the base URL of the peer owning the key
+func (c *Cache) Owner(key string) string {
+	return c.ring.GetNode(key)
+}
+
+// Get returns the value of the key from the cache, the owning peer or the
+// getter, in that order. The getter only runs here when this peer owns the
+// key or the owner cannot be reached; an error of the getter of the owner
+// is returned wrapping ErrPeer. The returned slice must not be modified.
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.stats.gets.Add(1)
+	if value, ok := c.main.get(key); ok {
+		c.stats.hits.Add(1)
+		return value, nil
+	}
+	if value, ok := c.hot.get(key); ok {
+		c.stats.hotHits.Add(1)
+		return value, nil
+	}
+
+	value, shared, err := c.flight.do(key, func() ([]byte, error) {
+		if owner := c.ring.GetNode(key); owner != c.self {
+			value, err := c.fetch(ctx, owner, key)
+			if err == nil {
+				c.stats.peerLoads.Add(1)
+				c.hot.add(key, value)
+				return value, nil
+			}
+			// The getter of the owner failed, and would fail here as well
+			if errors.Is(err, ErrPeer) || ctx.Err() != nil {
+				return nil, err
+			}
+			// The owner is unreachable, so load the key here rather than fail
+			c.stats.peerErrors.Add(1)
+		}
+		return c.load(ctx, key)
+	})
+	if shared {
+		c.stats.deduplicated.Add(1)
+	}
+	return value, err
+}
+
+// getLocally returns a key this peer was asked for by another one, never
+// forwarding the request, so peers with different views of the ring cannot
+// bounce it between them
+func (c *Cache) getLocally(ctx context.Context, key string) ([]byte, error) {
+	if value, ok := c.main.get(key); ok {
+		return value, nil
+	}
+	value, shared, err := c.flight.do(key, func() ([]byte, error) {
+		return c.load(ctx, key)
+	})
+	if shared {
+		c.stats.deduplicated.Add(1)
+	}
+	return value, err
+}
+
+// load calls the getter and caches the value as owned by this peer
+func (c *Cache) load(ctx context.Context, key string) ([]byte, error) {
+	// A load that finished just before this one started already cached it
+	if value, ok := c.main.get(key); ok {
+		return value, nil
+	}
+	c.stats.loads.Add(1)
+	value, err := c.getter(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	c.main.add(key, value)
+	return value, nil
+}
+
+// Stats returns the operation counters of the cache
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Gets:         c.stats.gets.Load(),
+		Hits:         c.stats.hits.Load(),
+		HotHits:      c.stats.hotHits.Load(),
+		Loads:        c.stats.loads.Load(),
+		PeerLoads:    c.stats.peerLoads.Load(),
+		PeerErrors:   c.stats.peerErrors.Load(),
+		Deduplicated: c.stats.deduplicated.Load(),
+		ServerGets:   c.stats.serverGets.Load(),
+	}
+}
+
+func peerURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return "", ErrInvalidPeer
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
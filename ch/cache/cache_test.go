@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// peer is a cache serving its keys on a loopback httptest server
+type peer struct {
+	cache  *Cache
+	server *httptest.Server
+	loads  atomic.Int64
+}
+
+var errBackend = errors.New("backend unavailable")
+
+// newCluster starts n peers mounted on a ServeMux, whose getters answer
+// "value-<key>" and fail for the keys starting with "fail"
+func newCluster(t *testing.T, n int) []*peer {
+	t.Helper()
+	peers := make([]*peer, n)
+	urls := make([]string, n)
+	for i := range peers {
+		p := &peer{server: httptest.NewUnstartedServer(nil)}
+		urls[i] = "http://" + p.server.Listener.Addr().String()
+
+		cache, err := New(Config{Self: urls[i]}, func(ctx context.Context, key string) ([]byte, error) {
+			p.loads.Add(1)
+			if strings.HasPrefix(key, "fail") {
+				return nil, errBackend
+			}
+			return []byte("value-" + key), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.cache = cache
+		mux := http.NewServeMux()
+		mux.Handle(DefaultBasePath, cache)
+		p.server.Config.Handler = mux
+		p.server.Start()
+		t.Cleanup(p.server.Close)
+		peers[i] = p
+	}
+	for _, p := range peers {
+		if err := p.cache.SetPeers(urls...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return peers
+}
+
+func TestCache_LoadsOnOwner(t *testing.T) {
+	peers := newCluster(t, 3)
+	ctx := context.Background()
+
+	keys := 30
+	for _, p := range peers {
+		for i := 0; i < keys; i++ {
+			key := "key/" + strconv.Itoa(i)
+			value, err := p.cache.Get(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(value) != "value-"+key {
+				t.Fatalf("Expected value-%s, got %s", key, value)
+			}
+		}
+	}
+
+	// Every key was loaded once, by its owner, whoever asked for it
+	total := int64(0)
+	for _, p := range peers {
+		total += p.loads.Load()
+		if p.loads.Load() == 0 {
+			t.Errorf("Expected %s to own some keys", p.cache.self)
+		}
+		if s := p.cache.Stats(); s.Loads != p.loads.Load() || s.PeerErrors != 0 {
+			t.Errorf("Unexpected stats of %s: %+v", p.cache.self, s)
+		}
+	}
+	if total != int64(keys) {
+		t.Errorf("Expected %d loads across the cluster, got %d", keys, total)
+	}
+}
+
+func TestCache_HotCache(t *testing.T) {
+	peers := newCluster(t, 2)
+	ctx := context.Background()
+
+	// A key owned by the second peer, asked for by the first
+	key := remoteKey(peers, "key")
+
+	for i := 0; i < 3; i++ {
+		if _, err := peers[0].cache.Get(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := peers[0].cache.Stats(); s.PeerLoads != 1 || s.HotHits != 2 {
+		t.Errorf("Expected one peer load and two hot hits, got %+v", s)
+	}
+	if s := peers[1].cache.Stats(); s.ServerGets != 1 {
+		t.Errorf("Expected the owner asked once, got %+v", s)
+	}
+}
+
+func TestCache_Singleflight(t *testing.T) {
+	release := make(chan struct{})
+	var loads atomic.Int64
+	cache, err := New(Config{Self: "http://127.0.0.1:1"}, func(ctx context.Context, key string) ([]byte, error) {
+		loads.Add(1)
+		<-release
+		return []byte(key), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, err := cache.Get(context.Background(), "key"); err != nil || string(value) != "key" {
+				t.Errorf("Expected key, got %q, %v", value, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads.Load() != 1 {
+		t.Errorf("Expected concurrent gets to share one load, got %d", loads.Load())
+	}
+	if s := cache.Stats(); s.Deduplicated+s.Hits != 19 {
+		t.Errorf("Expected 19 gets served without loading, got %+v", s)
+	}
+}
+
+func TestCache_PeerDown(t *testing.T) {
+	peers := newCluster(t, 2)
+	peers[1].server.Close()
+
+	key := remoteKey(peers, "key")
+	value, err := peers[0].cache.Get(context.Background(), key)
+	if err != nil || string(value) != "value-"+key {
+		t.Fatalf("Expected a local load when the owner is down, got %q, %v", value, err)
+	}
+	if s := peers[0].cache.Stats(); s.PeerErrors != 1 || s.Loads != 1 {
+		t.Errorf("Expected one peer error and a local load, got %+v", s)
+	}
+}
+
+// remoteKey returns a key with the prefix owned by the second peer
+func remoteKey(peers []*peer, prefix string) string {
+	for i := 0; ; i++ {
+		if k := prefix + strconv.Itoa(i); peers[0].cache.Owner(k) == peers[1].cache.self {
+			return k
+		}
+	}
+}
+
+func TestCache_KeysWithSlashes(t *testing.T) {
+	peers := newCluster(t, 2)
+	ctx := context.Background()
+
+	// Keys a ServeMux would clean and redirect if they were part of the path
+	for _, prefix := range []string{"/users/", "a//b/", "../x/", "a/./b/", "a b?c=d&e#f/"} {
+		key := remoteKey(peers, prefix)
+		value, err := peers[0].cache.Get(ctx, key)
+		if err != nil || string(value) != "value-"+key {
+			t.Fatalf("Expected value-%s, got %q, %v", key, value, err)
+		}
+		if _, ok := peers[1].cache.main.get(key); !ok {
+			t.Errorf("Expected the owner to cache %q under its own key", key)
+		}
+	}
+	if s := peers[0].cache.Stats(); s.Loads != 0 || s.PeerLoads != 5 {
+		t.Errorf("Expected every key fetched from its owner, got %+v", s)
+	}
+}
+
+func TestCache_OwnerGetterError(t *testing.T) {
+	peers := newCluster(t, 2)
+	key := remoteKey(peers, "fail")
+
+	// The getter of the owner failed, so the requester does not run its own
+	_, err := peers[0].cache.Get(context.Background(), key)
+	if !errors.Is(err, ErrPeer) || !strings.Contains(err.Error(), errBackend.Error()) {
+		t.Fatalf("Expected %v from the owner, got %v", ErrPeer, err)
+	}
+	if peers[0].loads.Load() != 0 || peers[1].loads.Load() != 1 {
+		t.Errorf("Expected only the owner to load, got %d and %d", peers[0].loads.Load(), peers[1].loads.Load())
+	}
+	if s := peers[0].cache.Stats(); s.PeerErrors != 0 || peers[0].cache.main.len() != 0 || peers[0].cache.hot.len() != 0 {
+		t.Errorf("Expected nothing cached and no fallback, got %+v", s)
+	}
+}
+
+func TestCache_GetterError(t *testing.T) {
+	cache, _ := New(Config{Self: "http://127.0.0.1:1"}, func(ctx context.Context, key string) ([]byte, error) {
+		return nil, errBackend
+	})
+
+	if _, err := cache.Get(context.Background(), "key"); err != errBackend {
+		t.Errorf("Expected %v, got %v", errBackend, err)
+	}
+	if cache.main.len() != 0 {
+		t.Error("Expected errors not to be cached")
+	}
+}
+
+func TestCache_Errors(t *testing.T) {
+	getter := func(ctx context.Context, key string) ([]byte, error) { return nil, nil }
+	if _, err := New(Config{Self: "http://127.0.0.1:1"}, nil); err != ErrNoGetter {
+		t.Errorf("Expected %v, got %v", ErrNoGetter, err)
+	}
+	if _, err := New(Config{Self: "127.0.0.1:1"}, getter); err != ErrInvalidPeer {
+		t.Errorf("Expected %v, got %v", ErrInvalidPeer, err)
+	}
+
+	cache, _ := New(Config{Self: "http://127.0.0.1:1"}, getter)
+	if err := cache.SetPeers("http://127.0.0.1:2", "/relative"); err != ErrInvalidPeer {
+		t.Errorf("Expected %v, got %v", ErrInvalidPeer, err)
+	}
+	if err := cache.SetPeers("http://127.0.0.1:2/"); err != nil {
+		t.Fatal(err)
+	}
+	if peers := cache.Peers(); len(peers) != 2 || peers[0] != "http://127.0.0.1:1" || peers[1] != "http://127.0.0.1:2" {
+		t.Errorf("Expected this peer kept on the ring, got %v", peers)
+	}
+}
@@ -0,0 +1,9 @@
+package cache
+
+import "errors"
+
+var (
+	ErrInvalidPeer = errors.New("peer must be an absolute URL with a host")
+	ErrNoGetter    = errors.New("cache needs a getter")
+	ErrPeer        = errors.New("peer failed to load the key")
+)
@@ -0,0 +1,26 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+func ExampleNew() {
+	c, err := New(Config{Self: "http://10.0.0.1:8080"}, func(ctx context.Context, key string) ([]byte, error) {
+		// Load the value from the source of truth, e.g. a database
+		return []byte("value of " + key), nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	_ = c.SetPeers("http://10.0.0.1:8080", "http://10.0.0.2:8080", "http://10.0.0.3:8080")
+	http.Handle(DefaultBasePath, c)
+
+	value, err := c.Get(context.Background(), "user:42")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(value))
+}
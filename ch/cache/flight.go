@@ -0,0 +1,42 @@
+package cache
+
+import "sync"
+
+// flight deduplicates concurrent loads of the same key: callers arriving
+// while a load runs wait for it and share its result
+type flight struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	wg    sync.WaitGroup
+	value []byte
+	err   error
+}
+
+// do runs fn for the key unless a call for it is running, and reports
+// whether the result was shared with another caller
+func (f *flight) do(key string, fn func() ([]byte, error)) ([]byte, bool, error) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]*call)
+	}
+	if c, ok := f.calls[key]; ok {
+		f.mu.Unlock()
+		c.wg.Wait()
+		return c.value, true, c.err
+	}
+	c := &call{}
+	c.wg.Add(1)
+	f.calls[key] = c
+	f.mu.Unlock()
+
+	c.value, c.err = fn()
+	c.wg.Done()
+
+	f.mu.Lock()
+	delete(f.calls, key)
+	f.mu.Unlock()
+	return c.value, false, c.err
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ServeHTTP answers the requests of other peers for the keys this peer
+// owns, at BasePath with the key in the query. The key is not part of the
+// path, so it is not cleaned or redirected by http.ServeMux.
+func (c *Cache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, c.basePath) {
+		http.NotFound(w, r)
+		return
+	}
+	key := r.URL.Query().Get(keyParam)
+	if key == "" {
+		http.Error(w, "missing key", http.StatusBadRequest)
+		return
+	}
+
+	c.stats.serverGets.Add(1)
+	value, err := c.getLocally(r.Context(), key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(value)
+}
+
+// keyParam is the query parameter carrying the key between peers
+const keyParam = "key"
+
+// fetch requests the key from the peer owning it. A peer that answered with
+// an error wraps ErrPeer; any other error means it could not be reached.
+func (c *Cache) fetch(ctx context.Context, peer, key string) ([]byte, error) {
+	query := url.Values{keyParam: {key}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, peer+c.basePath+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%w: %s returned %s: %s", ErrPeer, peer, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return io.ReadAll(resp.Body)
+}
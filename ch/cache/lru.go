@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru is a cache of values bounded by the bytes of its keys and values,
+// evicting the least recently used entries first
+type lru struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	items    map[string]*list.Element
+	order    *list.List // Front is the most recently used
+}
+
+type entry struct {
+	key   string
+	value []byte
+}
+
+func newLRU(maxBytes int64) *lru {
+	return &lru{
+		maxBytes: maxBytes,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *lru) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*entry).value, true
+}
+
+// add stores the value, unless it alone is larger than the cache
+func (c *lru) add(key string, value []byte) {
+	size := int64(len(key) + len(value))
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry)
+		c.bytes += int64(len(value) - len(e.value))
+		e.value = value
+		c.order.MoveToFront(el)
+	} else {
+		c.items[key] = c.order.PushFront(&entry{key: key, value: value})
+		c.bytes += size
+	}
+
+	for c.bytes > c.maxBytes {
+		el := c.order.Back()
+		e := el.Value.(*entry)
+		c.order.Remove(el)
+		delete(c.items, e.key)
+		c.bytes -= int64(len(e.key) + len(e.value))
+	}
+}
+
+func (c *lru) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
@@ -0,0 +1,30 @@
+package cache
+
+import "testing"
+
+func TestLRU_Eviction(t *testing.T) {
+	c := newLRU(10)
+	c.add("a", []byte("1234")) // 5 bytes
+	c.add("b", []byte("1234"))
+	c.get("a")
+	c.add("c", []byte("1234")) // Evicts b, the least recently used
+
+	if _, ok := c.get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Error("Expected a to be kept")
+	}
+	if c.bytes != 10 || c.len() != 2 {
+		t.Errorf("Expected 2 entries of 10 bytes, got %d of %d", c.len(), c.bytes)
+	}
+
+	c.add("a", []byte("12"))
+	if c.bytes != 8 {
+		t.Errorf("Expected replacing a value to update the size, got %d", c.bytes)
+	}
+	c.add("big", []byte("12345678"))
+	if _, ok := c.get("big"); ok || c.len() != 2 {
+		t.Error("Expected a value larger than the cache not to be stored")
+	}
+}
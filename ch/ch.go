@@ -1,10 +1,11 @@
 package ch
 
 import (
-	"hash/crc32"
 	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Hash function type
@@ -13,59 +14,341 @@ type Hash func(data []byte) uint32
 // Map represents the consistent hash ring with generics
 type Map[T any] struct {
 	mu       sync.RWMutex
-	hash     Hash
+	hash     Hash64
 	replicas int
-	keys     []int          // Sorted virtual node positions
-	hashMap  map[int]string // Virtual node hash -> Real node
+	keys     []uint64          // Sorted virtual node positions
+	hashMap  map[uint64]string // Virtual node hash -> Real node
 	data     map[string]T
+
+	ring    atomic.Pointer[ringSnapshot] // Latest ring, read by lookups without the lock
+	bounded atomic.Bool                  // Whether lookups must honour loads under the lock
+
+	hashName string // Built-in hash name, empty for a custom hash
+	hashBits int    // Size of the hash space, 32 or 64 bits
+
+	collisions map[uint64][]string // Virtual node hash -> Claims lost to the owner
+
+	partitions   map[string]map[string]struct{} // Real node -> Stored keys it owns
+	owners       map[string]string              // Stored key -> Real node
+	evictionHook func(node string, data map[string]T)
+
+	expiry         map[string]time.Time // Stored key -> Expiration, keys without a TTL are not listed
+	clock          func() time.Time     // Time source of expirations, time.Now when nil
+	expirationHook func(key string, value T)
+	janitorStop    chan struct{} // Closed to stop the running janitor
+	janitorDone    chan struct{} // Closed once the janitor returned
+
+	subscribers map[*subscriber]struct{}
+
+	topology map[string]Topology // Real node -> Failure domain labels
+
+	states map[string]NodeState // Real node -> State, active nodes are not listed
+
+	weights     map[string]int // Real node -> Weight
+	totalWeight int
+
+	loads      map[string]int // Real node -> Acquired load
+	totalLoad  int
+	loadFactor float64 // Bounded-load factor c, zero disables the bound
 }
 
-// New creates a new Consistent Hashing instance
+// New creates a new Consistent Hashing instance on a 32-bit hash ring,
+// hashing with crc32 when fn is nil
 func New[T any](replicas int, fn Hash) *Map[T] {
-	m := &Map[T]{
-		replicas: replicas,
-		hash:     fn,
-		hashMap:  make(map[int]string),
-		data:     make(map[string]T),
+	if fn == nil {
+		return newMap[T](replicas, CRC32, HashCRC32, 32)
 	}
-	if m.hash == nil {
-		m.hash = crc32.ChecksumIEEE
+	return newMap[T](replicas, func(data []byte) uint64 {
+		return uint64(fn(data))
+	}, "", 32)
+}
+
+// New64 creates a new Consistent Hashing instance on a 64-bit hash ring,
+// hashing with xxHash64 when fn is nil
+func New64[T any](replicas int, fn Hash64) *Map[T] {
+	if fn == nil {
+		return newMap[T](replicas, XXHash64, HashXXHash64, 64)
 	}
-	return m
+	return newMap[T](replicas, fn, "", 64)
 }
 
-// AddNode adds a node to the hash ring
-func (m *Map[T]) AddNode(node string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// NewWithHash creates a new Consistent Hashing instance hashing with the
+// built-in function registered under the name. crc32 gives a 32-bit hash
+// ring, the other built-in functions a 64-bit hash ring.
+func NewWithHash[T any](replicas int, name string) (*Map[T], error) {
+	fn, err := HashByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return newMap[T](replicas, fn, name, hashBits(name)), nil
+}
 
-	for i := 0; i < m.replicas; i++ {
-		hash := int(m.hash([]byte(strconv.Itoa(i) + node)))
-		m.keys = append(m.keys, hash)
-		m.hashMap[hash] = node
+func newMap[T any](replicas int, fn Hash64, name string, hashBits int) *Map[T] {
+	m := &Map[T]{
+		replicas:   replicas,
+		hash:       fn,
+		hashMap:    make(map[uint64]string),
+		data:       make(map[string]T),
+		hashName:   name,
+		hashBits:   hashBits,
+		collisions: make(map[uint64][]string),
+		partitions: make(map[string]map[string]struct{}),
+		owners:     make(map[string]string),
+		weights:    make(map[string]int),
+		loads:      make(map[string]int),
 	}
+	m.swap()
+	return m
+}
 
-	sort.Ints(m.keys)
+// HashName returns the name of the built-in hash function of the ring, an
+// empty string when the ring was created with a custom function
+func (m *Map[T]) HashName() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.hashName
+}
+
+// AddNode adds a node with weight 1 to the hash ring
+func (m *Map[T]) AddNode(node string) error {
+	return m.AddNodes(node)
 }
 
-// RemoveNode removes a node from the hash ring
+// RemoveNode removes a node from the hash ring. Its stored keys move to
+// their new owners after the eviction hook, if any, has seen them.
 func (m *Map[T]) RemoveNode(node string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.RemoveNodes(node)
+}
 
-	var newKeys []int
-	for _, hash := range m.keys {
+// ringUpdate collects the positions a batch of virtual node changes adds to
+// and removes from the ring, so the sorted positions are rewritten once
+type ringUpdate struct {
+	added   map[uint64]struct{}
+	removed map[uint64]struct{}
+}
+
+func newRingUpdate() *ringUpdate {
+	return &ringUpdate{
+		added:   make(map[uint64]struct{}),
+		removed: make(map[uint64]struct{}),
+	}
+}
+
+// change applies a batch of membership changes: the nodes of remove leave
+// the ring, then every node of set is added or moved to its weight. It
+// returns the events describing the batch, without ranges, and the data of
+// the removed partitions when an eviction hook is set. Callers must hold
+// the lock and have validated the batch.
+func (m *Map[T]) change(remove []string, set []nodeConfig) ([]Event, map[string]map[string]T) {
+	var events []Event
+	var evicted map[string]map[string]T
+	u := newRingUpdate()
+
+	for _, node := range remove {
+		weight, ok := m.weights[node]
+		if !ok {
+			continue
+		}
+		m.removeVnodes(node, 0, weight*m.replicas, u)
+		delete(m.weights, node)
+		delete(m.topology, node)
+		delete(m.states, node)
+		m.totalWeight -= weight
+
+		if load, ok := m.loads[node]; ok {
+			m.totalLoad -= load
+			delete(m.loads, node)
+		}
+
+		if m.evictionHook != nil {
+			if evicted == nil {
+				evicted = make(map[string]map[string]T)
+			}
+			data := make(map[string]T, len(m.partitions[node]))
+			for key := range m.partitions[node] {
+				data[key] = m.data[key]
+			}
+			evicted[node] = data
+		}
+		events = append(events, Event{Type: EventNodeRemoved, Node: node})
+	}
+
+	for _, n := range set {
+		old, ok := m.weights[n.Name]
+		switch {
+		case !ok:
+			m.loads[n.Name] = 0
+			m.addVnodes(n.Name, 0, n.Weight*m.replicas, u)
+			events = append(events, Event{Type: EventNodeAdded, Node: n.Name, Weight: n.Weight})
+		case n.Weight > old:
+			m.addVnodes(n.Name, old*m.replicas, n.Weight*m.replicas, u)
+			events = append(events, Event{Type: EventWeightChanged, Node: n.Name, Weight: n.Weight})
+		case n.Weight < old:
+			m.removeVnodes(n.Name, n.Weight*m.replicas, old*m.replicas, u)
+			events = append(events, Event{Type: EventWeightChanged, Node: n.Name, Weight: n.Weight})
+		default:
+			continue
+		}
+		m.weights[n.Name] = n.Weight
+		m.totalWeight += n.Weight - old
+	}
+
+	m.apply(u)
+	if len(events) > 0 {
+		m.swap()
+		m.repartition()
+	}
+	return events, evicted
+}
+
+// addVnodes claims the positions of the virtual nodes with index in
+// [from, to). A position claimed by several virtual nodes is owned by the
+// smallest node name, so the ring does not depend on the order nodes were
+// added in. Callers must hold the lock.
+func (m *Map[T]) addVnodes(node string, from, to int, u *ringUpdate) {
+	for i := from; i < to; i++ {
+		hash := m.vnodeHash(node, i)
+		owner, ok := m.hashMap[hash]
+		switch {
+		case !ok:
+			m.hashMap[hash] = node
+			// A position released earlier in the batch is still in keys
+			if _, ok := u.removed[hash]; ok {
+				delete(u.removed, hash)
+			} else {
+				u.added[hash] = struct{}{}
+			}
+		case node < owner:
+			m.collisions[hash] = append(m.collisions[hash], owner)
+			m.hashMap[hash] = node
+		default:
+			m.collisions[hash] = append(m.collisions[hash], node)
+		}
+	}
+}
+
+// removeVnodes releases the positions of the virtual nodes with index in
+// [from, to). A position still claimed by another virtual node passes to the
+// smallest remaining claimant. Callers must hold the lock.
+func (m *Map[T]) removeVnodes(node string, from, to int, u *ringUpdate) {
+	for i := from; i < to; i++ {
+		hash := m.vnodeHash(node, i)
+		claims := m.collisions[hash]
 		if m.hashMap[hash] != node {
-			newKeys = append(newKeys, hash)
+			for j, claim := range claims {
+				if claim == node {
+					claims = append(claims[:j], claims[j+1:]...)
+					break
+				}
+			}
+		} else if len(claims) > 0 {
+			next := 0
+			for j, claim := range claims {
+				if claim < claims[next] {
+					next = j
+				}
+			}
+			m.hashMap[hash] = claims[next]
+			claims = append(claims[:next], claims[next+1:]...)
 		} else {
 			delete(m.hashMap, hash)
+			// A position claimed earlier in the batch never made it to keys
+			if _, ok := u.added[hash]; ok {
+				delete(u.added, hash)
+			} else {
+				u.removed[hash] = struct{}{}
+			}
+		}
+
+		if len(claims) == 0 {
+			delete(m.collisions, hash)
+		} else {
+			m.collisions[hash] = claims
+		}
+	}
+}
+
+// apply rewrites the sorted positions with the changes of a batch. Only the
+// added positions are sorted, then merged with the remaining ones in a
+// single pass. Callers must hold the lock.
+func (m *Map[T]) apply(u *ringUpdate) {
+	if len(u.added) == 0 && len(u.removed) == 0 {
+		return
+	}
+
+	added := make([]uint64, 0, len(u.added))
+	for hash := range u.added {
+		added = append(added, hash)
+	}
+	sort.Slice(added, func(i, j int) bool {
+		return added[i] < added[j]
+	})
+
+	keys := make([]uint64, 0, len(m.keys)-len(u.removed)+len(added))
+	i := 0
+	for _, hash := range m.keys {
+		if _, ok := u.removed[hash]; ok {
+			continue
+		}
+		for i < len(added) && added[i] < hash {
+			keys = append(keys, added[i])
+			i++
 		}
+		keys = append(keys, hash)
 	}
-	m.keys = newKeys
+	m.keys = append(keys, added[i:]...)
 }
 
-// GetNode returns the closest node for the provided key
+// Collisions returns how many ring positions are claimed by more than one
+// virtual node. Each collision costs the losing node one virtual node.
+func (m *Map[T]) Collisions() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.collisions)
+}
+
+// vnodeHash returns the ring position of the i-th virtual node of a node
+func (m *Map[T]) vnodeHash(node string, i int) uint64 {
+	return m.hash([]byte(strconv.Itoa(i) + node))
+}
+
+// GetNode returns the closest node for the provided key, passing over down
+// nodes. Unless bounded loads are enabled it reads the latest ring snapshot
+// without taking the lock, so parallel lookups never contend with each other.
 func (m *Map[T]) GetNode(key string) string {
+	return m.getNode(func(s *ringSnapshot) uint64 {
+		return s.hash([]byte(key))
+	})
+}
+
+// GetNodeBytes returns the closest node for a key given as bytes, hashed in
+// place without converting it to a string
+func (m *Map[T]) GetNodeBytes(key []byte) string {
+	return m.getNode(func(s *ringSnapshot) uint64 {
+		return s.hash(key)
+	})
+}
+
+// GetNodeUint64 returns the closest node for a numeric key. Numeric keys are
+// not hashed as bytes but spread over the ring with the MurmurHash3
+// finalizer, truncated to the hash space of the ring, so the lookup never
+// allocates.
+func (m *Map[T]) GetNodeUint64(key uint64) string {
+	return m.getNode(func(s *ringSnapshot) uint64 {
+		return fmix64(key) >> (64 - s.hashBits)
+	})
+}
+
+// getNode returns the closest node for the position computed by keyHash
+func (m *Map[T]) getNode(keyHash func(s *ringSnapshot) uint64) string {
+	if !m.bounded.Load() {
+		s := m.snapshot()
+		if len(s.keys) == 0 {
+			return ""
+		}
+		return s.locate(keyHash(s)).Node
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -73,41 +356,155 @@ func (m *Map[T]) GetNode(key string) string {
 		return ""
 	}
 
-	hash := int(m.hash([]byte(key)))
+	return m.lookup(keyHash(m.snapshot()), StateDown)
+}
+
+// Nodes returns the nodes of the hash ring sorted by name
+func (m *Map[T]) Nodes() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	nodes := make([]string, 0, len(m.weights))
+	for node := range m.weights {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// GetNodes returns the owner of the key followed by the next n-1 distinct nodes
+// clockwise on the ring, in preference order. Load bounds are not applied and
+// down nodes are passed over.
+// ErrInsufficientNodes is returned when the ring has fewer than n nodes.
+func (m *Map[T]) GetNodes(key string, n int) ([]string, error) {
+	if n < 1 {
+		return nil, ErrInvalidCount
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.weights) < n || len(m.keys) == 0 {
+		return nil, ErrInsufficientNodes
+	}
+
+	nodes := make([]string, 0, n)
+	seen := make(map[string]struct{}, n)
+	idx := m.search(m.hash([]byte(key)))
+	for i := 0; i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if _, ok := seen[node]; ok || m.states[node] == StateDown {
+			continue
+		}
+		seen[node] = struct{}{}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) < n {
+		return nil, ErrInsufficientNodes
+	}
+	return nodes, nil
+}
+
+// search returns the index of the first virtual node clockwise from the hash.
+// Callers must hold the lock.
+func (m *Map[T]) search(hash uint64) int {
 	idx := sort.Search(len(m.keys), func(i int) bool {
 		return m.keys[i] >= hash
 	})
 	if idx == len(m.keys) {
 		idx = 0
 	}
-	return m.hashMap[m.keys[idx]]
+	return idx
 }
 
-// AddKey stores a key-value pair in the correct node
+// owner returns the node owning the key on the ring regardless of loads, an
+// empty string when the ring is empty. Callers must hold the lock.
+func (m *Map[T]) owner(key string) string {
+	if len(m.keys) == 0 {
+		return ""
+	}
+	return m.hashMap[m.keys[m.search(m.hash([]byte(key)))]]
+}
+
+// lookup walks the ring clockwise from the hash to the first node in a state
+// before skip, passing over full nodes when bounded loads are enabled. When
+// every such node is full the first one is returned, an empty string when
+// there is none. Callers must hold the lock.
+func (m *Map[T]) lookup(hash uint64, skip NodeState) string {
+	idx := m.search(hash)
+	first := ""
+	for i := 0; i < len(m.keys); i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if m.states[node] >= skip {
+			continue
+		}
+		if m.loadFactor == 0 || m.loads[node] < m.capacity(node) {
+			return node
+		}
+		if first == "" {
+			first = node
+		}
+	}
+	return first
+}
+
+// AddKey stores a key-value pair in the partition of the node owning the
+// key. The key never expires, even when it was stored with a TTL before.
 func (m *Map[T]) AddKey(key string, value T) {
-	node := m.GetNode(key)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.addKey(key, value) {
+		delete(m.expiry, key)
+	}
+}
+
+// addKey stores a key-value pair and reports whether a node owns the key.
+// Callers must hold the lock.
+func (m *Map[T]) addKey(key string, value T) bool {
+	node := m.owner(key)
 
 	// If no node found, no need to store the value
 	if node == "" {
-		return
+		return false
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	if _, ok := m.data[key]; !ok {
+		m.assign(key, node)
+	}
 	m.data[key] = value
+	return true
 }
 
 // RemoveKey deletes a key from the system
 func (m *Map[T]) RemoveKey(key string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+
+	if _, ok := m.data[key]; ok {
+		m.deleteKey(key)
+	}
+}
+
+// deleteKey removes a stored key. Callers must hold the lock.
+func (m *Map[T]) deleteKey(key string) {
+	m.unassign(key)
 	delete(m.data, key)
+	delete(m.expiry, key)
 }
 
-// GetKey retrieves a value stored in the system
+// GetKey retrieves a value stored in the system. An expired key is removed
+// and reported as missing.
 func (m *Map[T]) GetKey(key string) (T, bool) {
 	m.mu.RLock()
-	defer m.mu.RUnlock()
 	value, exists := m.data[key]
+	expired := exists && m.expired(key)
+	m.mu.RUnlock()
+
+	if expired {
+		m.expireKey(key)
+		var zero T
+		return zero, false
+	}
 	return value, exists
 }
@@ -1,6 +1,7 @@
 package ch
 
 import (
+	"hash/crc32"
 	"strconv"
 	"testing"
 )
@@ -139,3 +140,227 @@ func BenchmarkConsistentHashing_RemoveKey(b *testing.B) {
 		ch.RemoveKey("key" + strconv.Itoa(i%10000))
 	}
 }
+
+func TestConsistentHashing_GetNodes(t *testing.T) {
+	ch := New[string](20, nil)
+	for i := 0; i < 5; i++ {
+		ch.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	for i := 0; i < 100; i++ {
+		key := "key" + strconv.Itoa(i)
+		nodes, err := ch.GetNodes(key, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nodes) != 3 {
+			t.Fatalf("Expected 3 nodes, got %v", nodes)
+		}
+		if nodes[0] != ch.GetNode(key) {
+			t.Errorf("Expected primary %s first, got %v", ch.GetNode(key), nodes)
+		}
+		if nodes[0] == nodes[1] || nodes[1] == nodes[2] || nodes[0] == nodes[2] {
+			t.Errorf("Expected distinct nodes, got %v", nodes)
+		}
+	}
+}
+
+func TestConsistentHashing_GetNodesPreferenceOrder(t *testing.T) {
+	ch := New[string](20, nil)
+	for i := 0; i < 5; i++ {
+		ch.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	key := "user123"
+	nodes, _ := ch.GetNodes(key, 3)
+	ch.RemoveNode(nodes[0])
+
+	next, err := ch.GetNodes(key, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next[0] != nodes[1] || next[1] != nodes[2] {
+		t.Errorf("Expected %v after removing the primary, got %v", nodes[1:], next)
+	}
+}
+
+func TestConsistentHashing_GetNodesErrors(t *testing.T) {
+	ch := New[string](3, nil)
+	ch.AddNode("NodeA")
+	ch.AddNode("NodeB")
+
+	if _, err := ch.GetNodes("key", 3); err != ErrInsufficientNodes {
+		t.Errorf("Expected %v, got %v", ErrInsufficientNodes, err)
+	}
+	if _, err := ch.GetNodes("key", 0); err != ErrInvalidCount {
+		t.Errorf("Expected %v, got %v", ErrInvalidCount, err)
+	}
+	if nodes, err := ch.GetNodes("key", 2); err != nil || len(nodes) != 2 {
+		t.Errorf("Expected both nodes, got %v, %v", nodes, err)
+	}
+}
+
+func BenchmarkConsistentHashing_GetNodes(b *testing.B) {
+	ch := New[string](100, nil)
+	for i := 0; i < 1000; i++ {
+		ch.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = ch.GetNodes("key"+strconv.Itoa(i), 3)
+	}
+}
+
+func TestConsistentHashing_AddNodeDuplicate(t *testing.T) {
+	ch := New[string](3, nil)
+	if err := ch.AddNode("NodeA"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.AddNode("NodeA"); err != ErrNodeExists {
+		t.Errorf("Expected %v, got %v", ErrNodeExists, err)
+	}
+	if err := ch.AddNodeWithWeight("NodeA", 2); err != ErrNodeExists {
+		t.Errorf("Expected %v, got %v", ErrNodeExists, err)
+	}
+	if len(ch.keys) != 3 {
+		t.Errorf("Expected 3 virtual nodes, got %d", len(ch.keys))
+	}
+}
+
+// collidingHash squeezes crc32 into 64 positions so virtual nodes collide
+func collidingHash(data []byte) uint32 {
+	return crc32.ChecksumIEEE(data) % 64
+}
+
+func checkRing[T any](t *testing.T, ch *Map[T]) {
+	t.Helper()
+	if len(ch.keys) != len(ch.hashMap) {
+		t.Fatalf("Ring has %d positions but %d owners", len(ch.keys), len(ch.hashMap))
+	}
+	for i, hash := range ch.keys {
+		if i > 0 && ch.keys[i-1] >= hash {
+			t.Fatalf("Ring positions are not sorted and unique at %d", i)
+		}
+		if _, ok := ch.weights[ch.hashMap[hash]]; !ok {
+			t.Fatalf("Position %d owned by unknown node %q", hash, ch.hashMap[hash])
+		}
+	}
+}
+
+func TestConsistentHashing_CollisionsOrderIndependent(t *testing.T) {
+	a := New[string](50, collidingHash)
+	b := New[string](50, collidingHash)
+	_ = a.AddNode("NodeA")
+	_ = a.AddNode("NodeB")
+	_ = b.AddNode("NodeB")
+	_ = b.AddNode("NodeA")
+	checkRing(t, a)
+	checkRing(t, b)
+
+	if a.Collisions() == 0 {
+		t.Fatal("Expected colliding virtual nodes")
+	}
+	for hash, owner := range a.hashMap {
+		if b.hashMap[hash] != owner {
+			t.Fatalf("Position %d owned by %s and %s depending on insertion order", hash, owner, b.hashMap[hash])
+		}
+	}
+}
+
+func TestConsistentHashing_CollisionsRemoveNode(t *testing.T) {
+	for _, removed := range []string{"NodeA", "NodeB"} {
+		ch := New[string](50, collidingHash)
+		_ = ch.AddNode("NodeA")
+		_ = ch.AddNode("NodeB")
+		ch.RemoveNode(removed)
+		checkRing(t, ch)
+
+		kept := "NodeA"
+		if removed == kept {
+			kept = "NodeB"
+		}
+		for i := 0; i < 50; i++ {
+			if owner := ch.hashMap[ch.vnodeHash(kept, i)]; owner != kept {
+				t.Fatalf("Expected %s to own its virtual node %d after removing %s, got %q", kept, i, removed, owner)
+			}
+		}
+	}
+}
+
+func TestConsistentHashing_CollisionsRestore(t *testing.T) {
+	ch := New[string](30, collidingHash)
+	_ = ch.AddNode("NodeA")
+	_ = ch.AddNode("NodeC")
+	before := make(map[uint64]string, len(ch.hashMap))
+	for hash, owner := range ch.hashMap {
+		before[hash] = owner
+	}
+
+	_ = ch.AddNodeWithWeight("NodeB", 2)
+	_ = ch.SetWeight("NodeB", 1)
+	ch.RemoveNode("NodeB")
+	checkRing(t, ch)
+
+	if len(ch.hashMap) != len(before) {
+		t.Fatalf("Expected %d positions after restoring, got %d", len(before), len(ch.hashMap))
+	}
+	for hash, owner := range before {
+		if ch.hashMap[hash] != owner {
+			t.Fatalf("Position %d owned by %s, expected %s", hash, ch.hashMap[hash], owner)
+		}
+	}
+}
+
+func TestConsistentHashing_HashName(t *testing.T) {
+	if name := New[string](3, nil).HashName(); name != HashCRC32 {
+		t.Errorf("Expected %s, got %q", HashCRC32, name)
+	}
+	if name := New[string](3, collidingHash).HashName(); name != "" {
+		t.Errorf("Expected no name for a custom hash, got %q", name)
+	}
+	if name := New64[string](3, nil).HashName(); name != HashXXHash64 {
+		t.Errorf("Expected %s, got %q", HashXXHash64, name)
+	}
+
+	ch, err := NewWithHash[string](3, HashMurmur3)
+	if err != nil || ch.HashName() != HashMurmur3 || ch.hashBits != 64 {
+		t.Errorf("Expected a 64-bit murmur3 ring, got %v", err)
+	}
+	if _, err := NewWithHash[string](3, "sha1"); err != ErrUnknownHash {
+		t.Errorf("Expected %v, got %v", ErrUnknownHash, err)
+	}
+}
+
+func TestConsistentHashing_64BitPositions(t *testing.T) {
+	ch := New64[string](100, nil)
+	for i := 0; i < 10; i++ {
+		_ = ch.AddNode("Node" + strconv.Itoa(i))
+	}
+	checkRing(t, ch)
+
+	high := false
+	for _, hash := range ch.keys {
+		if hash > 1<<32 {
+			high = true
+			break
+		}
+	}
+	if !high {
+		t.Errorf("Expected virtual nodes beyond the 32-bit hash space")
+	}
+}
+
+func BenchmarkConsistentHashing_GetNode64(b *testing.B) {
+	ch := New64[string](100, nil)
+	for i := 0; i < 1000; i++ {
+		ch.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ch.GetNode("key" + strconv.Itoa(i))
+	}
+}
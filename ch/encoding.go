@@ -0,0 +1,262 @@
+package ch
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"sort"
+)
+
+// encodingVersion prefixes the binary encoding of a ring
+const encodingVersion = 1
+
+// MaxVirtualNodes bounds the virtual nodes of a decoded ring, its replicas
+// times the total weight of its nodes, so a corrupt or hostile encoding
+// cannot exhaust memory
+const MaxVirtualNodes = 1 << 24
+
+// ringConfig is the serialized form of a ring, everything needed to rebuild
+// the exact same virtual node positions
+type ringConfig struct {
+	Replicas int          `json:"replicas"`
+	Hash     string       `json:"hash"`
+	Nodes    []nodeConfig `json:"nodes"`
+}
+
+type nodeConfig struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// MarshalJSON encodes the ring configuration: replicas, hash function name,
+// and nodes with their weights. Stored keys and loads are not encoded.
+func (m *Map[T]) MarshalJSON() ([]byte, error) {
+	cfg, err := m.config()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(cfg)
+}
+
+// UnmarshalJSON rebuilds the ring from a configuration encoded by MarshalJSON.
+// A configuration above MaxVirtualNodes returns ErrInvalidEncoding.
+func (m *Map[T]) UnmarshalJSON(data []byte) error {
+	var cfg ringConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+	return m.restore(cfg)
+}
+
+// MarshalText encodes the ring configuration as JSON text
+func (m *Map[T]) MarshalText() ([]byte, error) {
+	return m.MarshalJSON()
+}
+
+// UnmarshalText rebuilds the ring from a configuration encoded by MarshalText
+func (m *Map[T]) UnmarshalText(text []byte) error {
+	return m.UnmarshalJSON(text)
+}
+
+// MarshalBinary encodes the ring configuration in a compact binary form
+func (m *Map[T]) MarshalBinary() ([]byte, error) {
+	cfg, err := m.config()
+	if err != nil {
+		return nil, err
+	}
+
+	data := []byte{encodingVersion}
+	data = binary.AppendUvarint(data, uint64(cfg.Replicas))
+	data = appendString(data, cfg.Hash)
+	data = binary.AppendUvarint(data, uint64(len(cfg.Nodes)))
+	for _, node := range cfg.Nodes {
+		data = appendString(data, node.Name)
+		data = binary.AppendUvarint(data, uint64(node.Weight))
+	}
+	return data, nil
+}
+
+// UnmarshalBinary rebuilds the ring from a configuration encoded by
+// MarshalBinary. A configuration above MaxVirtualNodes returns
+// ErrInvalidEncoding.
+func (m *Map[T]) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != encodingVersion {
+		return ErrInvalidEncoding
+	}
+	d := decoder{data: data[1:]}
+
+	var cfg ringConfig
+	cfg.Replicas = d.int()
+	cfg.Hash = d.string()
+	count := d.uvarint()
+	if d.err != nil || count > uint64(len(d.data)) {
+		return ErrInvalidEncoding
+	}
+	cfg.Nodes = make([]nodeConfig, count)
+	for i := range cfg.Nodes {
+		cfg.Nodes[i].Name = d.string()
+		cfg.Nodes[i].Weight = d.int()
+	}
+	if d.err != nil || len(d.data) != 0 {
+		return ErrInvalidEncoding
+	}
+	return m.restore(cfg)
+}
+
+// Fingerprint returns a hash of every virtual node position and its owner,
+// and of the nodes that are not active. Two rings with the same fingerprint
+// route every key to the same node with GetNode, which makes it a cheap
+// equality check across processes. Acquired loads are not covered.
+func (m *Map[T]) Fingerprint() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data := make([]byte, 0, len(m.keys)*16)
+	for _, hash := range m.keys {
+		data = binary.LittleEndian.AppendUint64(data, hash)
+		data = appendString(data, m.hashMap[hash])
+	}
+
+	// A ring without states hashes its layout alone
+	nodes := make([]string, 0, len(m.states))
+	for node := range m.states {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		data = appendString(data, node)
+		data = binary.AppendUvarint(data, uint64(m.states[node]))
+	}
+	return XXHash64(data)
+}
+
+// config returns the ring configuration with the nodes sorted by name
+func (m *Map[T]) config() (ringConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.hashName == "" {
+		return ringConfig{}, ErrUnnamedHash
+	}
+
+	cfg := ringConfig{
+		Replicas: m.replicas,
+		Hash:     m.hashName,
+		Nodes:    make([]nodeConfig, 0, len(m.weights)),
+	}
+	for node, weight := range m.weights {
+		cfg.Nodes = append(cfg.Nodes, nodeConfig{Name: node, Weight: weight})
+	}
+	sort.Slice(cfg.Nodes, func(i, j int) bool {
+		return cfg.Nodes[i].Name < cfg.Nodes[j].Name
+	})
+	return cfg, nil
+}
+
+// restore replaces the ring with the configuration. Stored keys are kept and
+// repartitioned, acquired loads, node states and topology labels are reset
+// and no events are published.
+func (m *Map[T]) restore(cfg ringConfig) error {
+	fn, err := HashByName(cfg.Hash)
+	if err != nil {
+		return err
+	}
+	if cfg.Replicas < 0 || cfg.Replicas > MaxVirtualNodes {
+		return ErrInvalidEncoding
+	}
+
+	// The total weight is checked against what is left of the budget, so
+	// the product of replicas and weights is never computed and cannot
+	// overflow
+	budget, total := MaxVirtualNodes, 0
+	if cfg.Replicas > 0 {
+		budget /= cfg.Replicas
+	}
+	seen := make(map[string]struct{}, len(cfg.Nodes))
+	for _, node := range cfg.Nodes {
+		if node.Weight < 1 {
+			return ErrInvalidWeight
+		}
+		if node.Weight > budget-total {
+			return ErrInvalidEncoding
+		}
+		total += node.Weight
+		if _, ok := seen[node.Name]; ok {
+			return ErrNodeExists
+		}
+		seen[node.Name] = struct{}{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A zero Map is restored as if it was created by NewWithHash
+	if m.data == nil {
+		m.data = make(map[string]T)
+		m.partitions = make(map[string]map[string]struct{})
+		m.owners = make(map[string]string)
+	}
+	m.replicas = cfg.Replicas
+	m.hash, m.hashName, m.hashBits = fn, cfg.Hash, hashBits(cfg.Hash)
+	m.keys = nil
+	m.hashMap = make(map[uint64]string)
+	m.collisions = make(map[uint64][]string)
+	m.weights, m.totalWeight = make(map[string]int), 0
+	m.loads, m.totalLoad = make(map[string]int), 0
+	m.states = nil
+	m.topology = nil
+
+	// Without nodes change leaves the ring alone, but the hash function may
+	// have changed and stored keys lost their owner
+	if events, _ := m.change(nil, cfg.Nodes); len(events) == 0 {
+		m.swap()
+		m.repartition()
+	}
+	return nil
+}
+
+func appendString(data []byte, s string) []byte {
+	data = binary.AppendUvarint(data, uint64(len(s)))
+	return append(data, s...)
+}
+
+// decoder reads the binary ring encoding, remembering the first error
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = ErrInvalidEncoding
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+// int reads a uvarint that must fit in an int32, so it converts to a
+// non-negative int on every platform
+func (d *decoder) int() int {
+	v := d.uvarint()
+	if v > math.MaxInt32 {
+		d.err = ErrInvalidEncoding
+		return 0
+	}
+	return int(v)
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil || n > uint64(len(d.data)) {
+		d.err = ErrInvalidEncoding
+		return ""
+	}
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s
+}
@@ -0,0 +1,245 @@
+package ch
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+func newEncodingMap(t *testing.T) *Map[string] {
+	t.Helper()
+	ch, err := NewWithHash[string](40, HashXXHash64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		_ = ch.AddNodeWithWeight("Node"+strconv.Itoa(i), i%3+1)
+	}
+	return ch
+}
+
+func checkSameRing[T any](t *testing.T, want, got *Map[T]) {
+	t.Helper()
+	if len(want.keys) != len(got.keys) {
+		t.Fatalf("Expected %d positions, got %d", len(want.keys), len(got.keys))
+	}
+	for i, hash := range want.keys {
+		if got.keys[i] != hash || got.hashMap[hash] != want.hashMap[hash] {
+			t.Fatalf("Position %d differs after restoring", i)
+		}
+	}
+	if want.Fingerprint() != got.Fingerprint() {
+		t.Fatalf("Expected the same fingerprint")
+	}
+	if got.replicas != want.replicas || got.HashName() != want.HashName() || got.hashBits != want.hashBits {
+		t.Fatalf("Expected the same configuration")
+	}
+}
+
+func TestEncoding_RoundTrip(t *testing.T) {
+	ch := newEncodingMap(t)
+
+	tests := []struct {
+		name      string
+		marshal   func() ([]byte, error)
+		unmarshal func(m *Map[string], data []byte) error
+	}{
+		{"JSON", ch.MarshalJSON, (*Map[string]).UnmarshalJSON},
+		{"Text", ch.MarshalText, (*Map[string]).UnmarshalText},
+		{"Binary", ch.MarshalBinary, (*Map[string]).UnmarshalBinary},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Restoring into a zero Map and into a populated Map gives the same ring
+			var zero Map[string]
+			if err := tt.unmarshal(&zero, data); err != nil {
+				t.Fatal(err)
+			}
+			checkSameRing(t, ch, &zero)
+
+			other := New[string](3, nil)
+			_ = other.AddNode("Other")
+			other.AddKey("key", "value")
+			if err := tt.unmarshal(other, data); err != nil {
+				t.Fatal(err)
+			}
+			checkSameRing(t, ch, other)
+			if keys := other.KeysForNode(other.GetNode("key")); len(keys) != 1 {
+				t.Errorf("Expected stored keys to be repartitioned, got %v", keys)
+			}
+
+			again, _ := tt.marshal()
+			if string(again) != string(data) {
+				t.Errorf("Expected a stable encoding")
+			}
+		})
+	}
+}
+
+func TestEncoding_JSONField(t *testing.T) {
+	type config struct {
+		Ring *Map[string] `json:"ring"`
+	}
+
+	ch := New[string](5, nil)
+	_ = ch.AddNode("NodeA")
+	_ = ch.AddNodeWithWeight("NodeB", 2)
+
+	data, err := json.Marshal(config{Ring: ch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"ring":{"replicas":5,"hash":"crc32","nodes":[{"name":"NodeA","weight":1},{"name":"NodeB","weight":2}]}}`
+	if string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+
+	var restored config
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	checkSameRing(t, ch, restored.Ring)
+}
+
+func TestEncoding_Errors(t *testing.T) {
+	custom := New[string](3, collidingHash)
+	if _, err := custom.MarshalJSON(); err != ErrUnnamedHash {
+		t.Errorf("Expected %v, got %v", ErrUnnamedHash, err)
+	}
+	if _, err := custom.MarshalBinary(); err != ErrUnnamedHash {
+		t.Errorf("Expected %v, got %v", ErrUnnamedHash, err)
+	}
+
+	var m Map[string]
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"Unknown hash", `{"replicas":3,"hash":"md5","nodes":[]}`, ErrUnknownHash},
+		{"Invalid weight", `{"replicas":3,"hash":"crc32","nodes":[{"name":"A","weight":0}]}`, ErrInvalidWeight},
+		{"Duplicate node", `{"replicas":3,"hash":"crc32","nodes":[{"name":"A","weight":1},{"name":"A","weight":1}]}`, ErrNodeExists},
+		{"Negative replicas", `{"replicas":-1,"hash":"crc32","nodes":[]}`, ErrInvalidEncoding},
+		{"Too many replicas", `{"replicas":16777217,"hash":"crc32","nodes":[]}`, ErrInvalidEncoding},
+		{"Too many virtual nodes", `{"replicas":4096,"hash":"crc32","nodes":[{"name":"A","weight":4096},{"name":"B","weight":1}]}`, ErrInvalidEncoding},
+		{"Overflowing weight", `{"replicas":2,"hash":"crc32","nodes":[{"name":"A","weight":9223372036854775807}]}`, ErrInvalidEncoding},
+	}
+	for _, tt := range tests {
+		if err := m.UnmarshalJSON([]byte(tt.data)); err != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	// Replicas and weights above an int32, and products above the limit
+	huge := func(replicas, weight uint64) []byte {
+		data := binary.AppendUvarint([]byte{encodingVersion}, replicas)
+		data = appendString(data, HashXXHash64)
+		data = binary.AppendUvarint(data, 1)
+		data = appendString(data, "A")
+		return binary.AppendUvarint(data, weight)
+	}
+
+	data, _ := newEncodingMap(t).MarshalBinary()
+	for _, bad := range [][]byte{
+		nil, {0}, data[:len(data)-1], append(data, 0),
+		huge(1<<33, 1), huge(1, 1<<33), huge(1<<63, 1), huge(1<<12, 1<<13), huge(1<<24, 2),
+	} {
+		if err := m.UnmarshalBinary(bad); err != ErrInvalidEncoding {
+			t.Errorf("Expected %v for %d bytes, got %v", ErrInvalidEncoding, len(bad), err)
+		}
+	}
+}
+
+func TestEncoding_MaxVirtualNodes(t *testing.T) {
+	var m Map[string]
+	// Replicas at the limit are accepted as long as the nodes stay within it
+	at := `{"replicas":16777216,"hash":"crc32","nodes":[]}`
+	if err := m.UnmarshalJSON([]byte(at)); err != nil {
+		t.Fatal(err)
+	}
+	within := `{"replicas":4096,"hash":"crc32","nodes":[{"name":"A","weight":2},{"name":"B","weight":1}]}`
+	if err := m.UnmarshalJSON([]byte(within)); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(m.snapshot().keys); n > 3*4096 || n < 3*4096-10 {
+		t.Errorf("Expected about %d virtual nodes, got %d", 3*4096, n)
+	}
+}
+
+func TestEncoding_ConcurrentHashName(t *testing.T) {
+	ch := newEncodingMap(t)
+	data, _ := ch.MarshalJSON()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = ch.UnmarshalJSON(data)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if name := ch.HashName(); name != HashXXHash64 {
+			t.Errorf("Expected %s during a restore, got %s", HashXXHash64, name)
+		}
+	}
+	<-done
+}
+
+func TestEncoding_Fingerprint(t *testing.T) {
+	a := New[string](20, nil)
+	b := New[string](20, nil)
+	for i := 0; i < 5; i++ {
+		_ = a.AddNode("Node" + strconv.Itoa(i))
+		_ = b.AddNode("Node" + strconv.Itoa(4-i))
+	}
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Fatal("Expected the same fingerprint regardless of insertion order")
+	}
+
+	_ = b.SetWeight("Node0", 2)
+	if a.Fingerprint() == b.Fingerprint() {
+		t.Error("Expected a different fingerprint after a weight change")
+	}
+	_ = b.SetWeight("Node0", 1)
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Error("Expected the fingerprint to return after restoring the weight")
+	}
+
+	// Down nodes change the routing, so they change the fingerprint
+	_ = b.SetState("Node2", StateDown)
+	if a.Fingerprint() == b.Fingerprint() {
+		t.Error("Expected a different fingerprint with a node down")
+	}
+	_ = b.SetState("Node2", StateActive)
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Error("Expected the fingerprint to return once the node is active")
+	}
+
+	c, _ := NewWithHash[string](20, HashMurmur3)
+	for i := 0; i < 5; i++ {
+		_ = c.AddNode("Node" + strconv.Itoa(i))
+	}
+	if a.Fingerprint() == c.Fingerprint() {
+		t.Error("Expected a different fingerprint with another hash function")
+	}
+}
+
+func BenchmarkFingerprint(b *testing.B) {
+	ch := New[string](100, nil)
+	for i := 0; i < 1000; i++ {
+		_ = ch.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ch.Fingerprint()
+	}
+}
@@ -0,0 +1,24 @@
+package ch
+
+import "errors"
+
+var (
+	ErrNoNodes             = errors.New("no nodes available in the hash ring")
+	ErrInvalidLoadFactor   = errors.New("load factor must be zero or at least 1")
+	ErrInvalidWeight       = errors.New("weight must be a positive integer")
+	ErrNodeNotFound        = errors.New("node is not in the hash ring")
+	ErrNodeExists          = errors.New("node is already in the hash ring")
+	ErrInvalidCount        = errors.New("node count must be positive")
+	ErrInsufficientNodes   = errors.New("not enough distinct nodes in the hash ring")
+	ErrInvalidTableSize    = errors.New("lookup table size must be a prime number")
+	ErrUnknownHash         = errors.New("unknown hash function name")
+	ErrUnnamedHash         = errors.New("ring with a custom hash function cannot be encoded")
+	ErrInvalidEncoding     = errors.New("invalid ring encoding")
+	ErrInvalidDomain       = errors.New("unknown failure domain level")
+	ErrInsufficientDomains = errors.New("not enough distinct failure domains in the hash ring")
+	ErrInvalidState        = errors.New("unknown node state")
+	ErrInvalidCapacity     = errors.New("capacity must be a positive number of buckets")
+	ErrCapacityExceeded    = errors.New("every bucket of the hash ring is in use")
+	ErrInvalidProbes       = errors.New("probe count must be positive")
+	ErrInvalidInterval     = errors.New("interval must be positive")
+)
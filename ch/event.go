@@ -0,0 +1,220 @@
+package ch
+
+import "sync"
+
+// EventType identifies a ring membership change
+type EventType int
+
+const (
+	EventNodeAdded EventType = iota + 1
+	EventNodeRemoved
+	EventWeightChanged
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventNodeAdded:
+		return "NodeAdded"
+	case EventNodeRemoved:
+		return "NodeRemoved"
+	case EventWeightChanged:
+		return "WeightChanged"
+	}
+	return "Unknown"
+}
+
+// Range is the arc of the hash space after Start up to and including End.
+// It wraps past zero when End is not greater than Start.
+type Range struct {
+	Start uint64
+	End   uint64
+}
+
+// Event describes a ring membership change
+type Event struct {
+	Type   EventType
+	Node   string
+	Weight int     // Weight after the change, zero when the node was removed
+	Ranges []Range // Hash ranges whose owner changed, in ring order, owned by the subscriber
+}
+
+// subscriber buffers events without bound so a slow reader never blocks
+// the ring, and delivers them in order from its own goroutine
+type subscriber struct {
+	mu     sync.Mutex
+	queue  []Event
+	notify chan struct{}
+	out    chan Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Subscribe returns a channel receiving every membership change of the ring
+// in order, and a function that cancels the subscription and closes the
+// channel. Events are buffered, so a slow subscriber never blocks AddNode.
+func (m *Map[T]) Subscribe() (<-chan Event, func()) {
+	s := &subscriber{
+		notify: make(chan struct{}, 1),
+		out:    make(chan Event),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+
+	m.mu.Lock()
+	if m.subscribers == nil {
+		m.subscribers = make(map[*subscriber]struct{})
+	}
+	m.subscribers[s] = struct{}{}
+	m.mu.Unlock()
+
+	return s.out, func() {
+		m.mu.Lock()
+		delete(m.subscribers, s)
+		m.mu.Unlock()
+		s.once.Do(func() { close(s.done) })
+	}
+}
+
+func (s *subscriber) push(e Event) {
+	s.mu.Lock()
+	s.queue = append(s.queue, e)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber) run() {
+	defer close(s.out)
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.notify:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		e := s.queue[0]
+		s.queue[0] = Event{}
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		select {
+		case s.out <- e:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// watch returns the ring before a change when anyone subscribed, nil
+// otherwise. Callers must hold the lock.
+func (m *Map[T]) watch() *ringSnapshot {
+	if len(m.subscribers) == 0 {
+		return nil
+	}
+	return m.snapshot()
+}
+
+// publish sends the events of a change with the ranges each node gained or
+// lost since the snapshot taken by watch. Callers must hold the lock.
+func (m *Map[T]) publish(before *ringSnapshot, events []Event) {
+	if before == nil || len(m.subscribers) == 0 {
+		return
+	}
+
+	ranges := changedRanges(before, m.snapshot())
+	for _, e := range events {
+		// Every subscriber owns its ranges, so it may sort or append in place
+		for s := range m.subscribers {
+			e.Ranges = append([]Range(nil), ranges[e.Node]...)
+			s.push(e)
+		}
+	}
+}
+
+// changedRanges returns the arcs of the hash space every node gained or lost
+// between the two snapshots, adjacent arcs merged. A batch of changes is
+// covered by a single pass: every changed arc goes to its old and new owner.
+func changedRanges(before, after *ringSnapshot) map[string][]Range {
+	ranges := make(map[string][]Range)
+	changedArcs(before, after, func(r Range, from, to string) {
+		if from != "" {
+			ranges[from] = appendRange(ranges[from], r)
+		}
+		if to != "" {
+			ranges[to] = appendRange(ranges[to], r)
+		}
+	})
+	for node := range ranges {
+		ranges[node] = joinWrap(ranges[node])
+	}
+	return ranges
+}
+
+// changedArcs calls fn with every arc whose owner differs between the two
+// snapshots, in ring order starting with the arc that wraps past zero. The
+// positions of both snapshots are sorted, so they are merged in linear time;
+// every arc between consecutive merged positions has a single owner in each.
+func changedArcs(before, after *ringSnapshot, fn func(r Range, from, to string)) {
+	nb, na := len(before.keys), len(after.keys)
+	if nb+na == 0 {
+		return
+	}
+
+	// The first arc starts at the highest position of either snapshot
+	var prev uint64
+	if nb > 0 {
+		prev = before.keys[nb-1]
+	}
+	if na > 0 && after.keys[na-1] > prev {
+		prev = after.keys[na-1]
+	}
+
+	i, j := 0, 0
+	for i < nb || j < na {
+		var end uint64
+		if j == na || (i < nb && before.keys[i] <= after.keys[j]) {
+			end = before.keys[i]
+		} else {
+			end = after.keys[j]
+		}
+
+		// i and j point at the first position of each snapshot at or after end
+		from, to := before.ownerAt(i), after.ownerAt(j)
+		if i < nb && before.keys[i] == end {
+			i++
+		}
+		if j < na && after.keys[j] == end {
+			j++
+		}
+		if from != to {
+			fn(Range{Start: prev, End: end}, from, to)
+		}
+		prev = end
+	}
+}
+
+// appendRange appends an arc, extending the last one when they are adjacent
+func appendRange(ranges []Range, r Range) []Range {
+	if n := len(ranges); n > 0 && ranges[n-1].End == r.Start {
+		ranges[n-1].End = r.End
+		return ranges
+	}
+	return append(ranges, r)
+}
+
+// joinWrap joins the last arc with the first one when they meet at the wrap
+// point
+func joinWrap(ranges []Range) []Range {
+	if n := len(ranges); n > 1 && ranges[n-1].End == ranges[0].Start {
+		ranges[0].Start = ranges[n-1].Start
+		ranges = ranges[:n-1]
+	}
+	return ranges
+}
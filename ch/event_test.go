@@ -0,0 +1,245 @@
+package ch
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+	return Event{}
+}
+
+// inRanges reports whether the hash falls in one of the ranges
+func inRanges(hash uint64, ranges []Range) bool {
+	for _, r := range ranges {
+		if r.Start < r.End && hash > r.Start && hash <= r.End {
+			return true
+		}
+		if r.Start >= r.End && (hash > r.Start || hash <= r.End) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEvent_Types(t *testing.T) {
+	ch := New[string](20, nil)
+	events, cancel := ch.Subscribe()
+	defer cancel()
+
+	_ = ch.AddNode("NodeA")
+	_ = ch.AddNodeWithWeight("NodeB", 2)
+	_ = ch.SetWeight("NodeB", 3)
+	_ = ch.SetWeight("NodeB", 3)
+	ch.RemoveNode("NodeA")
+	ch.RemoveNode("Missing")
+	_ = ch.AddNode("NodeB")
+
+	expected := []Event{
+		{Type: EventNodeAdded, Node: "NodeA", Weight: 1},
+		{Type: EventNodeAdded, Node: "NodeB", Weight: 2},
+		{Type: EventWeightChanged, Node: "NodeB", Weight: 3},
+		{Type: EventNodeRemoved, Node: "NodeA", Weight: 0},
+	}
+	for _, want := range expected {
+		got := nextEvent(t, events)
+		if got.Type != want.Type || got.Node != want.Node || got.Weight != want.Weight {
+			t.Fatalf("Expected %v %s weight %d, got %v %s weight %d",
+				want.Type, want.Node, want.Weight, got.Type, got.Node, got.Weight)
+		}
+		if len(got.Ranges) == 0 {
+			t.Errorf("Expected changed ranges for %v", got.Type)
+		}
+	}
+
+	select {
+	case e := <-events:
+		t.Errorf("Unexpected event %v %s", e.Type, e.Node)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestEvent_FirstNodeOwnsEverything(t *testing.T) {
+	ch := New[string](10, nil)
+	events, cancel := ch.Subscribe()
+	defer cancel()
+
+	_ = ch.AddNode("NodeA")
+	e := nextEvent(t, events)
+	if len(e.Ranges) != 1 || e.Ranges[0].Start != e.Ranges[0].End {
+		t.Errorf("Expected the whole ring as a single range, got %v", e.Ranges)
+	}
+}
+
+func TestEvent_RangesMatchMovedKeys(t *testing.T) {
+	ch := New[string](20, nil)
+	for i := 0; i < 5; i++ {
+		_ = ch.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	keys := 5000
+	before := make([]string, keys)
+	for i := range before {
+		before[i] = ch.GetNode("key" + strconv.Itoa(i))
+	}
+
+	events, cancel := ch.Subscribe()
+	defer cancel()
+	_ = ch.AddNode("Node5")
+	e := nextEvent(t, events)
+
+	for i := range before {
+		key := "key" + strconv.Itoa(i)
+		moved := ch.GetNode(key) != before[i]
+		if in := inRanges(ch.hash([]byte(key)), e.Ranges); in != moved {
+			t.Fatalf("Key %s moved %v but in changed ranges %v", key, moved, in)
+		}
+	}
+	for i := 1; i < len(e.Ranges); i++ {
+		if e.Ranges[i-1].End >= e.Ranges[i].Start {
+			t.Fatalf("Expected disjoint ranges in ring order, got %v", e.Ranges)
+		}
+	}
+}
+
+func TestEvent_BatchRangesMatchMovedKeys(t *testing.T) {
+	ch := New[string](20, nil)
+	_ = ch.SetNodes(map[string]int{"Node0": 1, "Node1": 1, "Node2": 2, "Node3": 1})
+
+	keys := 5000
+	before := make([]string, keys)
+	for i := range before {
+		before[i] = ch.GetNode("key" + strconv.Itoa(i))
+	}
+
+	events, cancel := ch.Subscribe()
+	defer cancel()
+	_ = ch.SetNodes(map[string]int{"Node0": 1, "Node2": 1, "Node3": 3, "Node4": 1, "Node5": 1})
+
+	// Every node of the batch gets the arcs it gained or lost
+	for n := 0; n < 5; n++ {
+		e := nextEvent(t, events)
+		for i := range before {
+			key := "key" + strconv.Itoa(i)
+			after := ch.GetNode(key)
+			moved := after != before[i] && (before[i] == e.Node || after == e.Node)
+			if in := inRanges(ch.hash([]byte(key)), e.Ranges); in != moved {
+				t.Fatalf("Key %s moved %v for %s but in changed ranges %v", key, moved, e.Node, in)
+			}
+		}
+	}
+}
+
+func BenchmarkEvent_AddNodesSubscribed(b *testing.B) {
+	nodes := make([]string, 500)
+	for i := range nodes {
+		nodes[i] = "Node" + strconv.Itoa(i)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ch := New64[string](200, nil)
+		events, cancel := ch.Subscribe()
+		go func() {
+			for range events {
+			}
+		}()
+		_ = ch.AddNodes(nodes...)
+		cancel()
+	}
+}
+
+func TestEvent_SubscribersOwnRanges(t *testing.T) {
+	ch := New[string](20, nil)
+	_ = ch.AddNode("NodeA")
+	first, cancelFirst := ch.Subscribe()
+	defer cancelFirst()
+	second, cancelSecond := ch.Subscribe()
+	defer cancelSecond()
+
+	_ = ch.AddNode("NodeB")
+	e := nextEvent(t, first)
+	want := append([]Range(nil), e.Ranges...)
+	for i := range e.Ranges {
+		e.Ranges[i] = Range{}
+	}
+	_ = append(e.Ranges[:0], Range{Start: 1, End: 2})
+
+	got := nextEvent(t, second).Ranges
+	if len(got) != len(want) || len(got) == 0 {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected a subscriber's changes to stay its own, got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestEvent_SlowSubscriberDoesNotBlock(t *testing.T) {
+	ch := New[string](10, nil)
+	events, cancel := ch.Subscribe()
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 500; i++ {
+			_ = ch.AddNode("Node" + strconv.Itoa(i))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("AddNode blocked on a subscriber that does not read")
+	}
+
+	for i := 0; i < 500; i++ {
+		if e := nextEvent(t, events); e.Node != "Node"+strconv.Itoa(i) {
+			t.Fatalf("Expected events in order, got %s at %d", e.Node, i)
+		}
+	}
+}
+
+func TestEvent_Cancel(t *testing.T) {
+	ch := New[string](10, nil)
+	events, cancel := ch.Subscribe()
+	cancel()
+	cancel()
+
+	_ = ch.AddNode("NodeA")
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Expected no event after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the channel to be closed")
+	}
+	if len(ch.subscribers) != 0 {
+		t.Errorf("Expected no subscribers, got %d", len(ch.subscribers))
+	}
+}
+
+func TestEvent_String(t *testing.T) {
+	tests := map[EventType]string{
+		EventNodeAdded:     "NodeAdded",
+		EventNodeRemoved:   "NodeRemoved",
+		EventWeightChanged: "WeightChanged",
+		EventType(0):       "Unknown",
+	}
+	for typ, want := range tests {
+		if typ.String() != want {
+			t.Errorf("Expected %s, got %s", want, typ.String())
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package gossip
+
+import "errors"
+
+var (
+	ErrNoTransport = errors.New("gossip needs a transport")
+	ErrClosed      = errors.New("gossip is closed")
+)
@@ -0,0 +1,24 @@
+package gossip
+
+import (
+	"fmt"
+	"log"
+)
+
+func ExampleNew() {
+	transport, err := NewUDPTransport("10.0.0.1:7946")
+	if err != nil {
+		log.Fatal(err)
+	}
+	g, err := New(Config{Transport: transport})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer g.Leave()
+
+	// Any member of the cluster is enough to join it
+	_ = g.Join("10.0.0.2:7946")
+
+	// The ring follows the membership, and routes the same way on every member
+	fmt.Println(g.Ring().GetNode("user:42"))
+}
@@ -0,0 +1,572 @@
+package gossip
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Ja7ad/algo/ch"
+)
+
+// Defaults of a Gossip
+const (
+	DefaultProbeInterval    = time.Second
+	DefaultProbeTimeout     = 500 * time.Millisecond
+	DefaultIndirectChecks   = 3
+	DefaultSuspicionTimeout = 5 * time.Second
+	DefaultPushPullInterval = 30 * time.Second
+	DefaultRetransmitMult   = 4
+	DefaultReplicas         = 100
+	DefaultTombstoneTimeout = 5 * time.Minute
+)
+
+// maxPiggyback bounds the updates attached to a single message
+const maxPiggyback = 8
+
+// maxStateSize bounds the updates of a single state packet, leaving room
+// for the rest of the message under maxPacketSize
+const maxStateSize = maxPacketSize - 1024
+
+// Config configures a Gossip
+type Config struct {
+	// Name identifies the member on the ring, the transport address when empty
+	Name string
+	// Transport carries the protocol packets
+	Transport Transport
+	// Ring is kept in sync with the alive and suspect members, a new 64-bit
+	// ch.Map with DefaultReplicas when nil
+	Ring ch.Ring
+	// ProbeInterval is the protocol period: every member probes another one
+	// per period, DefaultProbeInterval when zero
+	ProbeInterval time.Duration
+	// ProbeTimeout is how long a direct probe waits for its ack before asking
+	// other members to probe indirectly, DefaultProbeTimeout when zero
+	ProbeTimeout time.Duration
+	// IndirectChecks is the number of members asked to probe indirectly,
+	// DefaultIndirectChecks when zero
+	IndirectChecks int
+	// SuspicionTimeout is how long a suspect member has to refute the
+	// suspicion before it is declared dead, DefaultSuspicionTimeout when zero
+	SuspicionTimeout time.Duration
+	// PushPullInterval is the period of the full state exchange with a random
+	// member, which repairs what lost gossip missed, DefaultPushPullInterval
+	// when zero
+	PushPullInterval time.Duration
+	// RetransmitMult scales how many times each update is gossiped,
+	// DefaultRetransmitMult when zero
+	RetransmitMult int
+	// TombstoneTimeout is how long dead and left members are remembered,
+	// long enough for the news to reach every member, before they are
+	// forgotten, DefaultTombstoneTimeout when zero
+	TombstoneTimeout time.Duration
+}
+
+// Gossip keeps a consistent hash ring in sync across processes with the SWIM
+// protocol: members probe each other to detect failures, and spread joins,
+// leaves and failures by piggybacking them on the probes. A periodic full
+// state exchange repairs what lost packets missed, so the rings of all
+// members converge to the same fingerprint.
+type Gossip struct {
+	cfg       Config
+	transport Transport
+	ring      ch.Ring
+	name      string
+	addr      string
+
+	mu          sync.Mutex
+	incarnation uint64
+	members     map[string]*member // Other members, tombstones included
+	queue       []*broadcast
+	probes      []string // Members left to probe in this round
+	seeds       []string // Addresses to join again while no member is known
+	seq         uint32
+	acks        map[uint32]chan struct{} // Probe sequence -> Ack waiter
+	leaving     bool
+	closed      bool
+	rand        *rand.Rand
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// messageType identifies a protocol message
+type messageType int
+
+const (
+	messagePing messageType = iota + 1
+	messageAck
+	messagePingReq
+	messagePushPull
+	messagePushPullReply
+	messageState // Rest of a state too large for the push-pull packet
+)
+
+// message is the wire format of the protocol, JSON encoded in one packet
+type message struct {
+	Type       messageType `json:"type"`
+	Seq        uint32      `json:"seq,omitempty"`
+	Target     string      `json:"target,omitempty"`      // Name of the probed member
+	TargetAddr string      `json:"target_addr,omitempty"` // Address of the member to probe for a ping-req
+	Updates    []update    `json:"updates,omitempty"`     // Piggybacked news, or the full state of a push-pull
+}
+
+// New starts the membership of a single member, on the ring and alone until
+// it joins others
+func New(cfg Config) (*Gossip, error) {
+	if cfg.Transport == nil {
+		return nil, ErrNoTransport
+	}
+	if cfg.Name == "" {
+		cfg.Name = cfg.Transport.Addr()
+	}
+	if cfg.Ring == nil {
+		cfg.Ring = ch.New64[struct{}](DefaultReplicas, nil)
+	}
+	if cfg.ProbeInterval == 0 {
+		cfg.ProbeInterval = DefaultProbeInterval
+	}
+	if cfg.ProbeTimeout == 0 {
+		cfg.ProbeTimeout = DefaultProbeTimeout
+	}
+	if cfg.IndirectChecks == 0 {
+		cfg.IndirectChecks = DefaultIndirectChecks
+	}
+	if cfg.SuspicionTimeout == 0 {
+		cfg.SuspicionTimeout = DefaultSuspicionTimeout
+	}
+	if cfg.PushPullInterval == 0 {
+		cfg.PushPullInterval = DefaultPushPullInterval
+	}
+	if cfg.RetransmitMult == 0 {
+		cfg.RetransmitMult = DefaultRetransmitMult
+	}
+	if cfg.TombstoneTimeout == 0 {
+		cfg.TombstoneTimeout = DefaultTombstoneTimeout
+	}
+
+	g := &Gossip{
+		cfg:       cfg,
+		transport: cfg.Transport,
+		ring:      cfg.Ring,
+		name:      cfg.Name,
+		addr:      cfg.Transport.Addr(),
+		members:   make(map[string]*member),
+		acks:      make(map[uint32]chan struct{}),
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		done:      make(chan struct{}),
+	}
+	_ = g.ring.AddNode(g.name)
+
+	g.wg.Add(3)
+	go g.receive()
+	go g.probeLoop()
+	go g.pushPullLoop()
+	return g, nil
+}
+
+// Ring returns the ring kept in sync with the membership
+func (g *Gossip) Ring() ch.Ring {
+	return g.ring
+}
+
+// Name returns the name of this member
+func (g *Gossip) Name() string {
+	return g.name
+}
+
+// Members returns every member that is alive or suspect, this one included,
+// sorted by name
+func (g *Gossip) Members() []Member {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	members := []Member{g.self()}
+	for _, m := range g.members {
+		if m.State == StateAlive || m.State == StateSuspect {
+			members = append(members, m.Member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	return members
+}
+
+// Member returns what this member knows of the member with the name, dead
+// and left members included until their tombstone times out
+func (g *Gossip) Member(name string) (Member, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if name == g.name {
+		return g.self(), true
+	}
+	m, ok := g.members[name]
+	if !ok {
+		return Member{}, false
+	}
+	return m.Member, true
+}
+
+// Join exchanges the full membership state with the members at the
+// addresses. Packets may be lost, so the join completes asynchronously and
+// is retried every PushPullInterval until a member answers; any member of
+// the cluster is enough as a seed.
+func (g *Gossip) Join(seeds ...string) error {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return ErrClosed
+	}
+	g.seeds = nil
+	for _, seed := range seeds {
+		if seed != g.addr {
+			g.seeds = append(g.seeds, seed)
+		}
+	}
+	addrs := g.seeds
+	state := g.state()
+	g.mu.Unlock()
+
+	for _, addr := range addrs {
+		g.sendState(addr, messagePushPull, state)
+	}
+	return nil
+}
+
+// Leave tells the other members this one is leaving, so they take it off
+// their rings right away instead of waiting for the failure detector, then
+// closes the membership
+func (g *Gossip) Leave() error {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return ErrClosed
+	}
+	g.leaving = true
+	g.incarnation++
+	left := g.self()
+	left.State = StateLeft
+	msg := message{Type: messagePushPull, Updates: []update{left}}
+	var addrs []string
+	for _, m := range g.members {
+		if m.State == StateAlive || m.State == StateSuspect {
+			addrs = append(addrs, m.Addr)
+		}
+	}
+	g.mu.Unlock()
+
+	for _, addr := range addrs {
+		g.send(addr, msg)
+	}
+	return g.Close()
+}
+
+// Close stops the membership without telling the other members, who will
+// detect the failure
+func (g *Gossip) Close() error {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return ErrClosed
+	}
+	g.closed = true
+	g.mu.Unlock()
+
+	close(g.done)
+	err := g.transport.Close()
+	g.wg.Wait()
+	return err
+}
+
+// state returns the news about every member, this one included.
+// Callers must hold the lock.
+func (g *Gossip) state() []update {
+	updates := make([]update, 0, len(g.members)+1)
+	updates = append(updates, g.self())
+	for _, m := range g.members {
+		updates = append(updates, m.Member)
+	}
+	return updates
+}
+
+// sendState sends the updates of a full state exchange, split across as many
+// packets as needed to stay under maxPacketSize. Only the first packet has
+// the type, the others are state messages, so a push-pull is answered once.
+func (g *Gossip) sendState(addr string, typ messageType, updates []update) {
+	msg := message{Type: typ}
+	size := 0
+	for _, u := range updates {
+		data, err := json.Marshal(u)
+		if err != nil {
+			continue
+		}
+		if len(msg.Updates) > 0 && size+len(data)+1 > maxStateSize {
+			g.send(addr, msg)
+			msg = message{Type: messageState}
+			size = 0
+		}
+		msg.Updates = append(msg.Updates, u)
+		size += len(data) + 1
+	}
+	g.send(addr, msg)
+}
+
+func (g *Gossip) send(addr string, msg message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	_ = g.transport.Send(addr, data)
+}
+
+// reply sends a message with piggybacked news
+func (g *Gossip) reply(addr string, msg message) {
+	g.mu.Lock()
+	msg.Updates = append(msg.Updates, g.piggyback()...)
+	g.mu.Unlock()
+	g.send(addr, msg)
+}
+
+func (g *Gossip) receive() {
+	defer g.wg.Done()
+	for p := range g.transport.Packets() {
+		var msg message
+		if err := json.Unmarshal(p.Data, &msg); err != nil {
+			continue
+		}
+		g.handle(p.From, msg)
+	}
+}
+
+func (g *Gossip) handle(from string, msg message) {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return
+	}
+	for _, u := range msg.Updates {
+		g.apply(u)
+	}
+	var state []update
+	if msg.Type == messagePushPull {
+		state = g.state()
+	}
+	g.mu.Unlock()
+
+	switch msg.Type {
+	case messagePing:
+		// A ping for a previous member at this address is not ours to answer
+		if msg.Target == g.name {
+			g.reply(from, message{Type: messageAck, Seq: msg.Seq})
+		}
+	case messageAck:
+		g.mu.Lock()
+		if ack, ok := g.acks[msg.Seq]; ok {
+			close(ack)
+			delete(g.acks, msg.Seq)
+		}
+		g.mu.Unlock()
+	case messagePingReq:
+		g.wg.Add(1)
+		go g.probeFor(from, msg)
+	case messagePushPull:
+		g.sendState(from, messagePushPullReply, state)
+	}
+}
+
+// expect registers a probe sequence and returns the channel closed by its
+// ack. Callers must hold the lock.
+func (g *Gossip) expect() (uint32, chan struct{}) {
+	g.seq++
+	ack := make(chan struct{})
+	g.acks[g.seq] = ack
+	return g.seq, ack
+}
+
+// forget drops the waiter of a probe that timed out
+func (g *Gossip) forget(seq uint32) {
+	g.mu.Lock()
+	delete(g.acks, seq)
+	g.mu.Unlock()
+}
+
+// wait reports whether the ack arrives before the timeout or closing
+func (g *Gossip) wait(ack chan struct{}, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-ack:
+		return true
+	case <-timer.C:
+	case <-g.done:
+	}
+	return false
+}
+
+// probeFor probes a member on behalf of the one that sent the ping-req, and
+// forwards the ack
+func (g *Gossip) probeFor(from string, req message) {
+	defer g.wg.Done()
+
+	g.mu.Lock()
+	seq, ack := g.expect()
+	g.mu.Unlock()
+
+	g.reply(req.TargetAddr, message{Type: messagePing, Seq: seq, Target: req.Target})
+	if g.wait(ack, g.cfg.ProbeTimeout) {
+		g.reply(from, message{Type: messageAck, Seq: req.Seq})
+		return
+	}
+	g.forget(seq)
+}
+
+func (g *Gossip) probeLoop() {
+	defer g.wg.Done()
+	ticker := time.NewTicker(g.cfg.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			g.reap()
+			g.probe()
+		case <-g.done:
+			return
+		}
+	}
+}
+
+// probe runs one protocol period: ping the next member, ask others to ping
+// it when it does not answer in time, and suspect it when nobody could reach
+// it before the period ends
+func (g *Gossip) probe() {
+	g.mu.Lock()
+	target, ok := g.nextTarget()
+	if !ok {
+		g.mu.Unlock()
+		return
+	}
+	seq, ack := g.expect()
+	helpers := g.randomMembers(g.cfg.IndirectChecks, target.Name)
+	g.mu.Unlock()
+
+	g.reply(target.Addr, message{Type: messagePing, Seq: seq, Target: target.Name})
+	if g.wait(ack, g.cfg.ProbeTimeout) {
+		return
+	}
+
+	req := message{Type: messagePingReq, Seq: seq, Target: target.Name, TargetAddr: target.Addr}
+	for _, helper := range helpers {
+		g.reply(helper.Addr, req)
+	}
+	if g.wait(ack, g.cfg.ProbeInterval-g.cfg.ProbeTimeout) {
+		return
+	}
+	g.forget(seq)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.closed {
+		suspect := target
+		suspect.State = StateSuspect
+		g.apply(suspect)
+	}
+}
+
+// nextTarget returns the next member to probe, going through the alive and
+// suspect members in a random order every round. Callers must hold the lock.
+func (g *Gossip) nextTarget() (Member, bool) {
+	for {
+		if len(g.probes) == 0 {
+			for name, m := range g.members {
+				if m.State == StateAlive || m.State == StateSuspect {
+					g.probes = append(g.probes, name)
+				}
+			}
+			if len(g.probes) == 0 {
+				return Member{}, false
+			}
+			g.rand.Shuffle(len(g.probes), func(i, j int) {
+				g.probes[i], g.probes[j] = g.probes[j], g.probes[i]
+			})
+		}
+
+		name := g.probes[0]
+		g.probes = g.probes[1:]
+		if m, ok := g.members[name]; ok && (m.State == StateAlive || m.State == StateSuspect) {
+			return m.Member, true
+		}
+	}
+}
+
+// randomMembers returns up to n random alive members other than the
+// excluded one. Callers must hold the lock.
+func (g *Gossip) randomMembers(n int, exclude string) []Member {
+	var members []Member
+	for name, m := range g.members {
+		if name != exclude && m.State == StateAlive {
+			members = append(members, m.Member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	g.rand.Shuffle(len(members), func(i, j int) {
+		members[i], members[j] = members[j], members[i]
+	})
+	if len(members) > n {
+		members = members[:n]
+	}
+	return members
+}
+
+// reap declares dead the suspect members whose suspicion timed out, and
+// forgets the tombstones that timed out
+func (g *Gossip) reap() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	for name, m := range g.members {
+		if !now.After(m.deadline) {
+			continue
+		}
+		switch m.State {
+		case StateSuspect:
+			dead := m.Member
+			dead.State = StateDead
+			g.apply(dead)
+		case StateDead, StateLeft:
+			delete(g.members, name)
+		}
+	}
+}
+
+func (g *Gossip) pushPullLoop() {
+	defer g.wg.Done()
+	ticker := time.NewTicker(g.cfg.PushPullInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			g.mu.Lock()
+			var addrs []string
+			for _, peer := range g.randomMembers(1, "") {
+				addrs = append(addrs, peer.Addr)
+			}
+			if len(addrs) == 0 {
+				// The join was lost, or every member failed: retry the seeds
+				addrs = g.seeds
+			}
+			state := g.state()
+			g.mu.Unlock()
+			for _, addr := range addrs {
+				g.sendState(addr, messagePushPull, state)
+			}
+		case <-g.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package gossip
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ja7ad/algo/ch"
+)
+
+// fastConfig returns short protocol periods so tests converge quickly
+func fastConfig(t Transport) Config {
+	return Config{
+		Transport:        t,
+		ProbeInterval:    20 * time.Millisecond,
+		ProbeTimeout:     8 * time.Millisecond,
+		SuspicionTimeout: 100 * time.Millisecond,
+		PushPullInterval: 50 * time.Millisecond,
+	}
+}
+
+// newMemoryCluster starts n members on the network, all joined through the
+// first one
+func newMemoryCluster(t *testing.T, network *MemoryNetwork, n int) []*Gossip {
+	t.Helper()
+	members := make([]*Gossip, n)
+	for i := range members {
+		g, err := New(fastConfig(network.Transport("node-" + strconv.Itoa(i))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = g.Close() })
+		members[i] = g
+		if i > 0 {
+			if err := g.Join("node-0"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return members
+}
+
+// waitFor polls the condition until it holds or the timeout expires
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// converged reports whether every member sees exactly the names on its ring,
+// with the same fingerprint
+func converged(members []*Gossip, names []string) bool {
+	fingerprint := members[0].Ring().(*ch.Map[struct{}]).Fingerprint()
+	for _, g := range members {
+		if !reflect.DeepEqual(g.Ring().Nodes(), names) {
+			return false
+		}
+		if g.Ring().(*ch.Map[struct{}]).Fingerprint() != fingerprint {
+			return false
+		}
+	}
+	return true
+}
+
+func names(members []*Gossip) []string {
+	names := make([]string, len(members))
+	for i, g := range members {
+		names[i] = g.Name()
+	}
+	return names
+}
+
+func TestGossip_Converge(t *testing.T) {
+	members := newMemoryCluster(t, NewMemoryNetwork(0, 1), 8)
+	waitFor(t, 5*time.Second, func() bool { return converged(members, names(members)) })
+
+	for _, g := range members {
+		if got := len(g.Members()); got != len(members) {
+			t.Errorf("Expected %d members, got %d", len(members), got)
+		}
+	}
+}
+
+func TestGossip_ConvergeLossy(t *testing.T) {
+	network := NewMemoryNetwork(0.2, 1)
+	members := newMemoryCluster(t, network, 8)
+	waitFor(t, 10*time.Second, func() bool { return converged(members, names(members)) })
+
+	// Lost joins are repaired by the push-pull exchanges, and members only
+	// suspect each other on lost probes but refute it
+	time.Sleep(300 * time.Millisecond)
+	waitFor(t, 10*time.Second, func() bool { return converged(members, names(members)) })
+}
+
+func TestGossip_DetectFailure(t *testing.T) {
+	members := newMemoryCluster(t, NewMemoryNetwork(0, 1), 5)
+	waitFor(t, 5*time.Second, func() bool { return converged(members, names(members)) })
+
+	crashed := members[2]
+	_ = crashed.Close()
+	alive := append(members[:2:2], members[3:]...)
+	waitFor(t, 5*time.Second, func() bool { return converged(alive, names(alive)) })
+
+	m, ok := alive[0].Member(crashed.Name())
+	if !ok || m.State != StateDead {
+		t.Errorf("Expected %s to be dead, got %v", crashed.Name(), m.State)
+	}
+}
+
+func TestGossip_Leave(t *testing.T) {
+	members := newMemoryCluster(t, NewMemoryNetwork(0, 1), 4)
+	waitFor(t, 5*time.Second, func() bool { return converged(members, names(members)) })
+
+	if err := members[3].Leave(); err != nil {
+		t.Fatal(err)
+	}
+	if err := members[3].Leave(); err != ErrClosed {
+		t.Errorf("Expected %v, got %v", ErrClosed, err)
+	}
+
+	// A leave is announced, so the member is never suspected or declared dead
+	alive := members[:3]
+	waitFor(t, 5*time.Second, func() bool { return converged(alive, names(alive)) })
+	m, _ := alive[0].Member(members[3].Name())
+	if m.State != StateLeft {
+		t.Errorf("Expected %v, got %v", StateLeft, m.State)
+	}
+}
+
+func TestGossip_Refute(t *testing.T) {
+	members := newMemoryCluster(t, NewMemoryNetwork(0, 1), 3)
+	waitFor(t, 5*time.Second, func() bool { return converged(members, names(members)) })
+
+	// Member 0 wrongly suspects member 1, which hears of it and refutes
+	g := members[0]
+	target, _ := g.Member("node-1")
+	g.mu.Lock()
+	target.State = StateSuspect
+	g.apply(target)
+	g.mu.Unlock()
+
+	waitFor(t, 5*time.Second, func() bool {
+		m, _ := g.Member("node-1")
+		return m.State == StateAlive && m.Incarnation > target.Incarnation
+	})
+	if !converged(members, names(members)) {
+		t.Error("Expected a refuted suspicion to keep the member on the rings")
+	}
+}
+
+func TestGossip_Rejoin(t *testing.T) {
+	network := NewMemoryNetwork(0, 1)
+	members := newMemoryCluster(t, network, 3)
+	waitFor(t, 5*time.Second, func() bool { return converged(members, names(members)) })
+
+	_ = members[2].Leave()
+	waitFor(t, 5*time.Second, func() bool { return converged(members[:2], names(members[:2])) })
+
+	// A new process with the name starts from incarnation 0, below the
+	// tombstone, and takes over from it by refuting it
+	g, err := New(fastConfig(network.Transport("node-2")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = g.Close() })
+	_ = g.Join("node-0")
+
+	members[2] = g
+	waitFor(t, 5*time.Second, func() bool { return converged(members, names(members)) })
+}
+
+func TestGossip_LargeMembership(t *testing.T) {
+	network := NewMemoryNetwork(0, 1)
+	cfg := fastConfig(network.Transport("node-0"))
+	cfg.ProbeInterval = time.Hour
+	cfg.Ring = ch.New64[struct{}](1, nil)
+	a, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = a.Close() })
+
+	// Far more members than a single packet can carry
+	n := 1000
+	a.mu.Lock()
+	for i := 0; i < n; i++ {
+		name := "member-" + strconv.Itoa(i) + "." + strings.Repeat("x", 64)
+		a.apply(update{Name: name, Addr: name + ":7946", State: StateAlive})
+	}
+	state, _ := json.Marshal(message{Type: messagePushPull, Updates: a.state()})
+	a.mu.Unlock()
+	if len(state) <= maxPacketSize {
+		t.Fatalf("Expected a state larger than a packet, got %d bytes", len(state))
+	}
+
+	cfg = fastConfig(network.Transport("node-1"))
+	cfg.ProbeInterval = time.Hour
+	cfg.Ring = ch.New64[struct{}](1, nil)
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+	_ = b.Join("node-0")
+
+	// Both the join and its reply are split across packets
+	waitFor(t, 5*time.Second, func() bool {
+		return len(a.Members()) == n+2 && len(b.Members()) == n+2
+	})
+}
+
+func TestGossip_TombstoneTimeout(t *testing.T) {
+	network := NewMemoryNetwork(0, 1)
+	members := make([]*Gossip, 3)
+	for i := range members {
+		cfg := fastConfig(network.Transport("node-" + strconv.Itoa(i)))
+		cfg.TombstoneTimeout = 300 * time.Millisecond
+		g, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = g.Close() })
+		members[i] = g
+		if i > 0 {
+			_ = g.Join("node-0")
+		}
+	}
+	waitFor(t, 5*time.Second, func() bool { return converged(members, names(members)) })
+
+	_ = members[2].Leave()
+	alive := members[:2]
+	waitFor(t, 5*time.Second, func() bool { return converged(alive, names(alive)) })
+
+	// The tombstones are forgotten everywhere, and push-pull exchanges do not
+	// bring them back
+	forgotten := func() bool {
+		for _, g := range alive {
+			if _, ok := g.Member("node-2"); ok {
+				return false
+			}
+		}
+		return true
+	}
+	waitFor(t, 5*time.Second, forgotten)
+	time.Sleep(200 * time.Millisecond)
+	if !forgotten() {
+		t.Error("Expected forgotten tombstones to stay forgotten")
+	}
+	if !converged(alive, names(alive)) {
+		t.Error("Expected the rings unchanged by forgetting the tombstone")
+	}
+}
+
+func TestGossip_UDP(t *testing.T) {
+	members := make([]*Gossip, 3)
+	for i := range members {
+		transport, err := NewUDPTransport("127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := New(fastConfig(transport))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = g.Close() })
+		members[i] = g
+	}
+	for _, g := range members[1:] {
+		_ = g.Join(members[0].Name())
+	}
+
+	want := names(members)
+	sort.Strings(want)
+	waitFor(t, 5*time.Second, func() bool { return converged(members, want) })
+}
+
+func TestGossip_NoTransport(t *testing.T) {
+	if _, err := New(Config{}); err != ErrNoTransport {
+		t.Errorf("Expected %v, got %v", ErrNoTransport, err)
+	}
+}
+
+func TestMemberState_String(t *testing.T) {
+	for state, want := range map[MemberState]string{
+		StateAlive: "Alive", StateSuspect: "Suspect", StateDead: "Dead", StateLeft: "Left", 9: "Unknown",
+	} {
+		if got := state.String(); got != want {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package gossip
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// MemberState is the state of a member as known to this one
+type MemberState int
+
+const (
+	// StateAlive members answer probes and are on the ring
+	StateAlive MemberState = iota
+	// StateSuspect members missed a probe and are declared dead unless they
+	// refute the suspicion in time. They stay on the ring meanwhile.
+	StateSuspect
+	// StateDead members were confirmed failed and left the ring
+	StateDead
+	// StateLeft members left gracefully and left the ring
+	StateLeft
+)
+
+func (s MemberState) String() string {
+	switch s {
+	case StateAlive:
+		return "Alive"
+	case StateSuspect:
+		return "Suspect"
+	case StateDead:
+		return "Dead"
+	case StateLeft:
+		return "Left"
+	}
+	return "Unknown"
+}
+
+// Member is a process taking part in the membership
+type Member struct {
+	Name        string
+	Addr        string
+	State       MemberState
+	Incarnation uint64 // Raised by the member to refute suspicions about it
+}
+
+// member is the state of a member kept by this one
+type member struct {
+	Member
+	deadline time.Time // When a suspect member is declared dead, or a tombstone forgotten
+}
+
+// update is a piece of membership news spread by gossip. Dead and left
+// members are kept as tombstones for TombstoneTimeout, so older news cannot
+// bring them back while it may still be gossiped.
+type update = Member
+
+// broadcast is an update waiting to be piggybacked on outgoing messages
+type broadcast struct {
+	update    update
+	transmits int
+}
+
+// apply merges an update into the membership following the SWIM rules: news
+// with a higher incarnation wins, and at equal incarnation suspect beats
+// alive while dead and left beat both. Applied news is gossiped further.
+// Callers must hold the lock.
+func (g *Gossip) apply(u update) {
+	if u.Name == g.name {
+		// Refute news of our suspicion or death with a new incarnation
+		if u.State != StateAlive && !g.leaving && u.Incarnation >= g.incarnation {
+			g.incarnation = u.Incarnation + 1
+			g.broadcast(g.self())
+		}
+		return
+	}
+
+	m, ok := g.members[u.Name]
+	gone := ok && (m.State == StateDead || m.State == StateLeft)
+	switch u.State {
+	case StateAlive:
+		if ok && u.Incarnation <= m.Incarnation {
+			return
+		}
+		if !ok || gone {
+			_ = g.ring.AddNode(u.Name)
+		}
+	case StateSuspect:
+		if !ok || gone || u.Incarnation < m.Incarnation ||
+			(m.State == StateSuspect && u.Incarnation == m.Incarnation) {
+			return
+		}
+	case StateDead, StateLeft:
+		// News of the death of an unknown member is ignored, so members do not
+		// gossip forgotten tombstones back to each other
+		if !ok || gone || u.Incarnation < m.Incarnation {
+			return
+		}
+		g.ring.RemoveNode(u.Name)
+	default:
+		return
+	}
+
+	if !ok {
+		m = &member{}
+		g.members[u.Name] = m
+	}
+	m.Member = u
+	switch u.State {
+	case StateSuspect:
+		m.deadline = time.Now().Add(g.cfg.SuspicionTimeout)
+	case StateDead, StateLeft:
+		m.deadline = time.Now().Add(g.cfg.TombstoneTimeout)
+	}
+	g.broadcast(u)
+}
+
+// self returns the news that this member is alive. Callers must hold the lock.
+func (g *Gossip) self() update {
+	return update{Name: g.name, Addr: g.addr, State: StateAlive, Incarnation: g.incarnation}
+}
+
+// broadcast queues an update for gossip, replacing older news about the same
+// member. Callers must hold the lock.
+func (g *Gossip) broadcast(u update) {
+	for i, b := range g.queue {
+		if b.update.Name == u.Name {
+			g.queue = append(g.queue[:i], g.queue[i+1:]...)
+			break
+		}
+	}
+	g.queue = append(g.queue, &broadcast{update: u})
+}
+
+// piggyback returns the updates to attach to an outgoing message, the least
+// transmitted first. An update is dropped once it was sent
+// RetransmitMult*log2(members) times, enough to reach every member with
+// high probability. Callers must hold the lock.
+func (g *Gossip) piggyback() []update {
+	if len(g.queue) == 0 {
+		return nil
+	}
+	sort.SliceStable(g.queue, func(i, j int) bool {
+		return g.queue[i].transmits < g.queue[j].transmits
+	})
+
+	limit := g.cfg.RetransmitMult * int(math.Ceil(math.Log2(float64(len(g.members)+2))))
+	n := len(g.queue)
+	if n > maxPiggyback {
+		n = maxPiggyback
+	}
+	updates := make([]update, n)
+	kept := g.queue[:0]
+	for i, b := range g.queue {
+		if i < n {
+			updates[i] = b.update
+			b.transmits++
+		}
+		if b.transmits < limit {
+			kept = append(kept, b)
+		}
+	}
+	g.queue = kept
+	return updates
+}
@@ -0,0 +1,96 @@
+package gossip
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// MemoryNetwork connects in-memory transports, dropping a share of the
+// packets to simulate a lossy network in tests
+type MemoryNetwork struct {
+	mu         sync.Mutex
+	rand       *rand.Rand
+	loss       float64
+	transports map[string]*MemoryTransport
+}
+
+// NewMemoryNetwork creates a network losing each packet with probability
+// loss, drawn from a source seeded with seed
+func NewMemoryNetwork(loss float64, seed int64) *MemoryNetwork {
+	return &MemoryNetwork{
+		rand:       rand.New(rand.NewSource(seed)),
+		loss:       loss,
+		transports: make(map[string]*MemoryTransport),
+	}
+}
+
+// SetLoss changes the probability of losing a packet
+func (n *MemoryNetwork) SetLoss(loss float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.loss = loss
+}
+
+// Transport attaches a transport with the address to the network
+func (n *MemoryNetwork) Transport(addr string) *MemoryTransport {
+	t := &MemoryTransport{
+		network: n,
+		addr:    addr,
+		packets: make(chan Packet, 1024),
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.transports[addr] = t
+	return t
+}
+
+// MemoryTransport is a Transport on a MemoryNetwork
+type MemoryTransport struct {
+	network *MemoryNetwork
+	addr    string
+	packets chan Packet
+}
+
+// Addr returns the address of the transport on its network
+func (t *MemoryTransport) Addr() string {
+	return t.addr
+}
+
+// Send delivers a copy of the packet, unless the network loses it, the
+// address is unknown, the packet is larger than UDP allows or the receiver
+// is not keeping up
+func (t *MemoryTransport) Send(to string, data []byte) error {
+	n := t.network
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	dst, ok := n.transports[to]
+	if !ok || len(data) > maxPacketSize || n.rand.Float64() < n.loss {
+		return nil
+	}
+	packet := Packet{From: t.addr, Data: append([]byte(nil), data...)}
+	select {
+	case dst.packets <- packet:
+	default:
+	}
+	return nil
+}
+
+// Packets returns the received packets
+func (t *MemoryTransport) Packets() <-chan Packet {
+	return t.packets
+}
+
+// Close detaches the transport from the network
+func (t *MemoryTransport) Close() error {
+	n := t.network
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.transports[t.addr] == t {
+		delete(n.transports, t.addr)
+		close(t.packets)
+	}
+	return nil
+}
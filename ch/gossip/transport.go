@@ -0,0 +1,104 @@
+package gossip
+
+import (
+	"net"
+	"sync"
+)
+
+// Packet is a message received from another member
+type Packet struct {
+	From string // Transport address of the sender
+	Data []byte
+}
+
+// Transport sends and receives the packets of the protocol. Delivery is best
+// effort: packets may be lost, duplicated or reordered, as with UDP.
+type Transport interface {
+	// Addr returns the address other members reach this one at
+	Addr() string
+	// Send delivers a packet to the address, or drops it
+	Send(to string, data []byte) error
+	// Packets returns the received packets, closed by Close
+	Packets() <-chan Packet
+	// Close stops the transport
+	Close() error
+}
+
+// maxPacketSize is the largest UDP payload. Larger messages are split by the
+// protocol, and dropped by the transports.
+const maxPacketSize = 65507
+
+// UDPTransport is a Transport over UDP
+type UDPTransport struct {
+	conn    net.PacketConn
+	packets chan Packet
+
+	mu    sync.Mutex
+	addrs map[string]*net.UDPAddr // Resolved addresses
+}
+
+// NewUDPTransport listens for packets on the address, such as
+// "127.0.0.1:7946", or "127.0.0.1:0" for a free port
+func NewUDPTransport(addr string) (*UDPTransport, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	t := &UDPTransport{
+		conn:    conn,
+		packets: make(chan Packet, 256),
+		addrs:   make(map[string]*net.UDPAddr),
+	}
+	go t.read()
+	return t, nil
+}
+
+func (t *UDPTransport) read() {
+	defer close(t.packets)
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := t.conn.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			return
+		}
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		t.packets <- Packet{From: from.String(), Data: data}
+	}
+}
+
+// Addr returns the local address of the transport
+func (t *UDPTransport) Addr() string {
+	return t.conn.LocalAddr().String()
+}
+
+// Send writes the packet to the address
+func (t *UDPTransport) Send(to string, data []byte) error {
+	t.mu.Lock()
+	addr, ok := t.addrs[to]
+	t.mu.Unlock()
+	if !ok {
+		var err error
+		if addr, err = net.ResolveUDPAddr("udp", to); err != nil {
+			return err
+		}
+		t.mu.Lock()
+		t.addrs[to] = addr
+		t.mu.Unlock()
+	}
+	_, err := t.conn.WriteTo(data, addr)
+	return err
+}
+
+// Packets returns the received packets
+func (t *UDPTransport) Packets() <-chan Packet {
+	return t.packets
+}
+
+// Close closes the socket
+func (t *UDPTransport) Close() error {
+	return t.conn.Close()
+}
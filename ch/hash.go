@@ -0,0 +1,206 @@
+package ch
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math/bits"
+)
+
+// Hash64 function type for rings with a 64-bit hash space
+type Hash64 func(data []byte) uint64
+
+// Names of the built-in hash functions
+const (
+	HashCRC32    = "crc32"
+	HashXXHash64 = "xxhash64"
+	HashMurmur3  = "murmur3"
+	HashFNV1a    = "fnv1a"
+)
+
+var hashes = map[string]Hash64{
+	HashCRC32:    CRC32,
+	HashXXHash64: XXHash64,
+	HashMurmur3:  Murmur3,
+	HashFNV1a:    FNV1a,
+}
+
+// HashByName returns the built-in hash function registered under the name
+func HashByName(name string) (Hash64, error) {
+	fn, ok := hashes[name]
+	if !ok {
+		return nil, ErrUnknownHash
+	}
+	return fn, nil
+}
+
+// hashBits returns the size of the hash space of a built-in hash function
+func hashBits(name string) int {
+	if name == HashCRC32 {
+		return 32
+	}
+	return 64
+}
+
+// CRC32 returns the IEEE crc32 checksum of data, the default 32-bit hash
+func CRC32(data []byte) uint64 {
+	return uint64(crc32.ChecksumIEEE(data))
+}
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// FNV1a returns the 64-bit FNV-1a hash of data passed through fmix64. The
+// last bytes of plain FNV-1a barely reach its high bits, so short keys that
+// differ at the end would cluster on the ring; the finalizer spreads them.
+func FNV1a(data []byte) uint64 {
+	h := uint64(fnvOffset64)
+	for _, c := range data {
+		h ^= uint64(c)
+		h *= fnvPrime64
+	}
+	return fmix64(h)
+}
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// XXHash64 returns the xxHash64 of data with a zero seed
+func XXHash64(data []byte) uint64 {
+	n := len(data)
+	var h uint64
+
+	if n >= 32 {
+		p1, p2 := xxPrime1, xxPrime2
+		v1 := p1 + p2
+		v2 := p2
+		v3 := uint64(0)
+		v4 := -p1
+		for ; len(data) >= 32; data = data[32:] {
+			v1 = xxRound(v1, binary.LittleEndian.Uint64(data[0:8]))
+			v2 = xxRound(v2, binary.LittleEndian.Uint64(data[8:16]))
+			v3 = xxRound(v3, binary.LittleEndian.Uint64(data[16:24]))
+			v4 = xxRound(v4, binary.LittleEndian.Uint64(data[24:32]))
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) +
+			bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMergeRound(h, v1)
+		h = xxMergeRound(h, v2)
+		h = xxMergeRound(h, v3)
+		h = xxMergeRound(h, v4)
+	} else {
+		h = xxPrime5
+	}
+	h += uint64(n)
+
+	for ; len(data) >= 8; data = data[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(data))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(data) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(data)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		data = data[4:]
+	}
+	for _, c := range data {
+		h ^= uint64(c) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMergeRound(acc, val uint64) uint64 {
+	acc ^= xxRound(0, val)
+	return acc*xxPrime1 + xxPrime4
+}
+
+const (
+	murmurC1 uint64 = 0x87c37b91114253d5
+	murmurC2 uint64 = 0x4cf5ad432745937f
+)
+
+// Murmur3 returns the first 64 bits of the x64 128-bit MurmurHash3 of data
+// with a zero seed
+func Murmur3(data []byte) uint64 {
+	n := len(data)
+	var h1, h2 uint64
+
+	for ; len(data) >= 16; data = data[16:] {
+		k1 := binary.LittleEndian.Uint64(data[0:8])
+		k2 := binary.LittleEndian.Uint64(data[8:16])
+
+		h1 ^= murmurMixK1(k1)
+		h1 = bits.RotateLeft64(h1, 27) + h2
+		h1 = h1*5 + 0x52dce729
+
+		h2 ^= murmurMixK2(k2)
+		h2 = bits.RotateLeft64(h2, 31) + h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	if len(data) > 8 {
+		var k2 uint64
+		for i := len(data) - 1; i >= 8; i-- {
+			k2 = k2<<8 | uint64(data[i])
+		}
+		h2 ^= murmurMixK2(k2)
+		data = data[:8]
+	}
+	if len(data) > 0 {
+		var k1 uint64
+		for i := len(data) - 1; i >= 0; i-- {
+			k1 = k1<<8 | uint64(data[i])
+		}
+		h1 ^= murmurMixK1(k1)
+	}
+
+	h1 ^= uint64(n)
+	h2 ^= uint64(n)
+	h1 += h2
+	h2 += h1
+	h1 = fmix64(h1)
+	h2 = fmix64(h2)
+	h1 += h2
+	return h1
+}
+
+func murmurMixK1(k uint64) uint64 {
+	k *= murmurC1
+	k = bits.RotateLeft64(k, 31)
+	return k * murmurC2
+}
+
+func murmurMixK2(k uint64) uint64 {
+	k *= murmurC2
+	k = bits.RotateLeft64(k, 33)
+	return k * murmurC1
+}
+
+// fmix64 is the 64-bit finalizer of MurmurHash3, it spreads every input bit
+// across the whole output.
+func fmix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,30 @@
+package ch
+
+import "hash/crc32"
+
+// JumpHash maps a 64-bit key to a bucket in [0, buckets) using the jump
+// consistent hash of Lamping and Veach. Growing the bucket count from n to
+// n+1 moves only the keys that land in the new bucket. It returns -1 when
+// buckets is not positive.
+func JumpHash(key uint64, buckets int) int {
+	if buckets <= 0 {
+		return -1
+	}
+
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// JumpHashString hashes a string key with fn, crc32 when nil, and maps it to
+// a bucket in [0, buckets) with JumpHash.
+func JumpHashString(key string, buckets int, fn Hash) int {
+	if fn == nil {
+		fn = crc32.ChecksumIEEE
+	}
+	return JumpHash(uint64(fn([]byte(key))), buckets)
+}
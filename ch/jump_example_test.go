@@ -0,0 +1,10 @@
+package ch
+
+import "fmt"
+
+func ExampleJumpHash() {
+	// Shard numbered buckets without keeping a ring in memory
+	for _, key := range []uint64{1, 42, 256} {
+		fmt.Println(key, "->", JumpHash(key, 1024))
+	}
+}
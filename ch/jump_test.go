@@ -0,0 +1,139 @@
+package ch
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestJumpHash_InvalidBuckets(t *testing.T) {
+	if b := JumpHash(42, 0); b != -1 {
+		t.Errorf("Expected -1 for zero buckets, got %d", b)
+	}
+	if b := JumpHashString("key", -3, nil); b != -1 {
+		t.Errorf("Expected -1 for negative buckets, got %d", b)
+	}
+}
+
+func TestJumpHash_KnownValues(t *testing.T) {
+	// Reference values of the Lamping & Veach implementation
+	tests := []struct {
+		key     uint64
+		buckets int
+		want    int
+	}{
+		{1, 1, 0},
+		{42, 57, 43},
+		{0xDEAD10CC, 1, 0},
+		{0xDEAD10CC, 666, 361},
+		{256, 1024, 520},
+	}
+
+	for _, tt := range tests {
+		if got := JumpHash(tt.key, tt.buckets); got != tt.want {
+			t.Errorf("JumpHash(%d, %d) = %d, want %d", tt.key, tt.buckets, got, tt.want)
+		}
+	}
+}
+
+func TestJumpHash_Deterministic(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		b := JumpHashString(key, 10, nil)
+		if b < 0 || b >= 10 {
+			t.Fatalf("Bucket %d out of range", b)
+		}
+		if again := JumpHashString(key, 10, nil); again != b {
+			t.Fatalf("Expected consistent mapping for %s, got %d and %d", key, b, again)
+		}
+	}
+}
+
+func TestJumpHash_MinimalMovementAgainstMap(t *testing.T) {
+	const keys, buckets = 20000, 10
+
+	ring := New[string](100, nil)
+	for i := 0; i < buckets; i++ {
+		ring.AddNode(strconv.Itoa(i))
+	}
+
+	jumpBefore := make([]int, keys)
+	ringBefore := make([]string, keys)
+	for i := 0; i < keys; i++ {
+		key := "key" + strconv.Itoa(i)
+		jumpBefore[i] = JumpHashString(key, buckets, nil)
+		ringBefore[i] = ring.GetNode(key)
+	}
+
+	ring.AddNode(strconv.Itoa(buckets))
+	jumpMoved, ringMoved := 0, 0
+	for i := 0; i < keys; i++ {
+		key := "key" + strconv.Itoa(i)
+		if b := JumpHashString(key, buckets+1, nil); b != jumpBefore[i] {
+			if b != buckets {
+				t.Fatalf("Key %s moved from bucket %d to old bucket %d", key, jumpBefore[i], b)
+			}
+			jumpMoved++
+		}
+		if node := ring.GetNode(key); node != ringBefore[i] {
+			if node != strconv.Itoa(buckets) {
+				t.Fatalf("Key %s moved from node %s to old node %s", key, ringBefore[i], node)
+			}
+			ringMoved++
+		}
+	}
+
+	// Both should move about K/(N+1) keys, jump hash with far less variance
+	expected := float64(keys) / float64(buckets+1)
+	if float64(jumpMoved) < expected*0.8 || float64(jumpMoved) > expected*1.2 {
+		t.Errorf("Jump hash moved %d keys, expected about %.0f", jumpMoved, expected)
+	}
+	if ringMoved == 0 {
+		t.Errorf("Expected the ring to move keys to the new node")
+	}
+}
+
+func TestJumpHash_BalanceAgainstMap(t *testing.T) {
+	const keys, buckets = 50000, 10
+
+	ring := New[string](100, nil)
+	for i := 0; i < buckets; i++ {
+		ring.AddNode(strconv.Itoa(i))
+	}
+
+	jumpCounts := make(map[int]int)
+	ringCounts := make(map[string]int)
+	for i := 0; i < keys; i++ {
+		key := "key" + strconv.Itoa(i)
+		jumpCounts[JumpHashString(key, buckets, nil)]++
+		ringCounts[ring.GetNode(key)]++
+	}
+
+	jumpMax, ringMax := 0, 0
+	for _, c := range jumpCounts {
+		if c > jumpMax {
+			jumpMax = c
+		}
+	}
+	for _, c := range ringCounts {
+		if c > ringMax {
+			ringMax = c
+		}
+	}
+	if jumpMax > ringMax {
+		t.Errorf("Expected jump hash peak load %d to be at most the ring peak load %d", jumpMax, ringMax)
+	}
+}
+
+func BenchmarkJumpHash(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = JumpHash(uint64(i), 1000)
+	}
+}
+
+func BenchmarkJumpHashString(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = JumpHashString("key"+strconv.Itoa(i), 1000, nil)
+	}
+}
@@ -0,0 +1,208 @@
+package ch
+
+import (
+	"hash/crc32"
+	"sort"
+	"sync"
+)
+
+// DefaultMaglevTableSize is the lookup table size used when none is provided
+const DefaultMaglevTableSize = 65537
+
+// Maglev represents Google's Maglev hashing. Every node fills a prime-sized
+// lookup table following its own permutation of the slots, which gives O(1)
+// lookups, near-perfect balance and little disruption when nodes change.
+type Maglev struct {
+	mu    sync.RWMutex
+	hash  Hash
+	size  uint64
+	nodes []maglevNode // Sorted by name
+	table []int        // Slot -> Index in nodes, -1 when the table is empty
+}
+
+type maglevNode struct {
+	name   string
+	offset uint64
+	skip   uint64
+	weight int
+}
+
+// NewMaglev creates a new Maglev hashing instance with a lookup table of the
+// provided prime size, DefaultMaglevTableSize when zero. The table should be
+// much larger than the number of nodes, at least 100 times for a 1% imbalance.
+func NewMaglev(size int, fn Hash) (*Maglev, error) {
+	if size == 0 {
+		size = DefaultMaglevTableSize
+	}
+	if !isPrime(size) {
+		return nil, ErrInvalidTableSize
+	}
+
+	mg := &Maglev{
+		hash:  fn,
+		size:  uint64(size),
+		table: make([]int, size),
+	}
+	if mg.hash == nil {
+		mg.hash = crc32.ChecksumIEEE
+	}
+	mg.populate()
+	return mg, nil
+}
+
+// AddNode adds a node with weight 1 and rebuilds the lookup table
+func (mg *Maglev) AddNode(node string) error {
+	return mg.AddNodeWithWeight(node, 1)
+}
+
+// AddNodeWithWeight adds a node that fills a share of the lookup table
+// proportional to its weight and rebuilds the table
+func (mg *Maglev) AddNodeWithWeight(node string, weight int) error {
+	if weight < 1 {
+		return ErrInvalidWeight
+	}
+
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	idx := mg.index(node)
+	if idx < len(mg.nodes) && mg.nodes[idx].name == node {
+		return ErrNodeExists
+	}
+
+	mg.nodes = append(mg.nodes, maglevNode{})
+	copy(mg.nodes[idx+1:], mg.nodes[idx:])
+	mg.nodes[idx] = maglevNode{
+		name:   node,
+		offset: fmix64(uint64(mg.hash([]byte(node)))) % mg.size,
+		skip:   fmix64(uint64(mg.hash([]byte(node+"#skip"))))%(mg.size-1) + 1,
+		weight: weight,
+	}
+	mg.populate()
+	return nil
+}
+
+// RemoveNode removes a node and rebuilds the lookup table
+func (mg *Maglev) RemoveNode(node string) {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	idx := mg.index(node)
+	if idx < len(mg.nodes) && mg.nodes[idx].name == node {
+		mg.nodes = append(mg.nodes[:idx], mg.nodes[idx+1:]...)
+		mg.populate()
+	}
+}
+
+// SetWeight changes the weight of a node and rebuilds the lookup table
+func (mg *Maglev) SetWeight(node string, weight int) error {
+	if weight < 1 {
+		return ErrInvalidWeight
+	}
+
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	idx := mg.index(node)
+	if idx == len(mg.nodes) || mg.nodes[idx].name != node {
+		return ErrNodeNotFound
+	}
+	mg.nodes[idx].weight = weight
+	mg.populate()
+	return nil
+}
+
+// Weight returns the weight of a node, zero when the node is not present
+func (mg *Maglev) Weight(node string) int {
+	mg.mu.RLock()
+	defer mg.mu.RUnlock()
+
+	idx := mg.index(node)
+	if idx < len(mg.nodes) && mg.nodes[idx].name == node {
+		return mg.nodes[idx].weight
+	}
+	return 0
+}
+
+// Nodes returns the nodes sorted by name
+func (mg *Maglev) Nodes() []string {
+	mg.mu.RLock()
+	defer mg.mu.RUnlock()
+
+	nodes := make([]string, len(mg.nodes))
+	for i := range mg.nodes {
+		nodes[i] = mg.nodes[i].name
+	}
+	return nodes
+}
+
+// TableSize returns the size of the lookup table
+func (mg *Maglev) TableSize() int {
+	return int(mg.size)
+}
+
+// GetNode returns the node in the lookup table slot of the provided key
+func (mg *Maglev) GetNode(key string) string {
+	mg.mu.RLock()
+	defer mg.mu.RUnlock()
+
+	if len(mg.nodes) == 0 {
+		return ""
+	}
+	return mg.nodes[mg.table[uint64(mg.hash([]byte(key)))%mg.size]].name
+}
+
+// populate rebuilds the lookup table. Nodes take turns in name order, each
+// claiming weight slots per turn at the next free position of its
+// permutation (offset + j*skip) mod size. Callers must hold the lock.
+func (mg *Maglev) populate() {
+	for i := range mg.table {
+		mg.table[i] = -1
+	}
+	if len(mg.nodes) == 0 {
+		return
+	}
+
+	pos := make([]uint64, len(mg.nodes))
+	for i := range mg.nodes {
+		pos[i] = mg.nodes[i].offset
+	}
+
+	filled := uint64(0)
+	for {
+		for i := range mg.nodes {
+			n := &mg.nodes[i]
+			for w := 0; w < n.weight; w++ {
+				for mg.table[pos[i]] >= 0 {
+					pos[i] = (pos[i] + n.skip) % mg.size
+				}
+				mg.table[pos[i]] = i
+				filled++
+				if filled == mg.size {
+					return
+				}
+			}
+		}
+	}
+}
+
+// index returns the position of the node in the sorted node list, or where
+// it would be inserted. Callers must hold the lock.
+func (mg *Maglev) index(node string) int {
+	return sort.Search(len(mg.nodes), func(i int) bool {
+		return mg.nodes[i].name >= node
+	})
+}
+
+// isPrime reports whether n is a prime number
+func isPrime(n int) bool {
+	if n < 2 {
+		return false
+	}
+	for i := 2; i*i <= n; i++ {
+		if n%i == 0 {
+			return false
+		}
+	}
+	return true
+}
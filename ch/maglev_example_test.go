@@ -0,0 +1,19 @@
+package ch
+
+import (
+	"fmt"
+	"log"
+)
+
+func ExampleNewMaglev() {
+	mg, err := NewMaglev(DefaultMaglevTableSize, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mg.AddNode("10.0.0.1:80")
+	mg.AddNode("10.0.0.2:80")
+	_ = mg.AddNodeWithWeight("10.0.0.3:80", 2)
+
+	fmt.Println("Backend:", mg.GetNode("198.51.100.7:51234"))
+}
@@ -0,0 +1,161 @@
+package ch
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestMaglev_InvalidTableSize(t *testing.T) {
+	for _, size := range []int{-7, 1, 4, 65536} {
+		if _, err := NewMaglev(size, nil); err != ErrInvalidTableSize {
+			t.Errorf("Expected %v for size %d, got %v", ErrInvalidTableSize, size, err)
+		}
+	}
+
+	mg, err := NewMaglev(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mg.TableSize() != DefaultMaglevTableSize {
+		t.Errorf("Expected default table size, got %d", mg.TableSize())
+	}
+}
+
+func TestMaglev_GetNode(t *testing.T) {
+	mg, _ := NewMaglev(0, nil)
+	if node := mg.GetNode("key"); node != "" {
+		t.Errorf("Expected empty string without nodes, got %s", node)
+	}
+
+	mg.AddNode("NodeA")
+	mg.AddNode("NodeB")
+	mg.AddNode("NodeC")
+
+	node := mg.GetNode("my-key")
+	if node == "" {
+		t.Errorf("Expected a valid node, but got an empty string")
+	}
+	if again := mg.GetNode("my-key"); again != node {
+		t.Errorf("Expected consistent mapping, but got %s and %s", node, again)
+	}
+}
+
+func maglevSlots(mg *Maglev) map[string]int {
+	slots := make(map[string]int)
+	for _, idx := range mg.table {
+		slots[mg.nodes[idx].name]++
+	}
+	return slots
+}
+
+func TestMaglev_Balance(t *testing.T) {
+	mg, _ := NewMaglev(65537, nil)
+	for i := 0; i < 7; i++ {
+		mg.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	for node, c := range maglevSlots(mg) {
+		if c != 65537/7 && c != 65537/7+1 {
+			t.Errorf("Node %s owns %d slots, expected %d or %d", node, c, 65537/7, 65537/7+1)
+		}
+	}
+}
+
+func TestMaglev_Weights(t *testing.T) {
+	mg, _ := NewMaglev(10007, nil)
+	if err := mg.AddNodeWithWeight("Big", 0); err != ErrInvalidWeight {
+		t.Errorf("Expected %v, got %v", ErrInvalidWeight, err)
+	}
+	if err := mg.SetWeight("Big", 3); err != ErrNodeNotFound {
+		t.Errorf("Expected %v, got %v", ErrNodeNotFound, err)
+	}
+
+	_ = mg.AddNodeWithWeight("Big", 3)
+	mg.AddNode("Small")
+
+	slots := maglevSlots(mg)
+	if share := float64(slots["Big"]) / 10007; share < 0.74 || share > 0.76 {
+		t.Errorf("Expected Big to own 75%% of slots, got %.3f", share)
+	}
+
+	_ = mg.SetWeight("Big", 1)
+	if mg.Weight("Big") != 1 {
+		t.Errorf("Expected weight 1, got %d", mg.Weight("Big"))
+	}
+	slots = maglevSlots(mg)
+	if diff := slots["Big"] - slots["Small"]; diff < -1 || diff > 1 {
+		t.Errorf("Expected equal slots after resetting the weight, got %v", slots)
+	}
+}
+
+func TestMaglev_MinimalDisruption(t *testing.T) {
+	mg, _ := NewMaglev(65537, nil)
+	for i := 0; i < 10; i++ {
+		mg.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	before := make([]string, mg.size)
+	for slot, idx := range mg.table {
+		before[slot] = mg.nodes[idx].name
+	}
+
+	mg.RemoveNode("Node3")
+	disrupted := 0
+	for slot, idx := range mg.table {
+		after := mg.nodes[idx].name
+		if after == "Node3" {
+			t.Fatalf("Slot %d still points to the removed node", slot)
+		}
+		if before[slot] != "Node3" && after != before[slot] {
+			disrupted++
+		}
+	}
+
+	// Only the removed node's slots must move, allow a few percent of churn
+	if ratio := float64(disrupted) / float64(mg.size); ratio > 0.05 {
+		t.Errorf("Expected little disruption, %.3f of the table changed owner", ratio)
+	}
+}
+
+func TestMaglev_InsertionOrderIndependent(t *testing.T) {
+	a, _ := NewMaglev(1009, nil)
+	b, _ := NewMaglev(1009, nil)
+	for i := 0; i < 10; i++ {
+		a.AddNode("Node" + strconv.Itoa(i))
+		b.AddNode("Node" + strconv.Itoa(9-i))
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		if a.GetNode(key) != b.GetNode(key) {
+			t.Fatalf("Expected the same owner for %s regardless of insertion order", key)
+		}
+	}
+}
+
+func BenchmarkMaglev_GetNode(b *testing.B) {
+	mg, _ := NewMaglev(65537, nil)
+	for i := 0; i < 100; i++ {
+		mg.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = mg.GetNode("key" + strconv.Itoa(i))
+	}
+}
+
+func BenchmarkMaglev_AddNode(b *testing.B) {
+	mg, _ := NewMaglev(65537, nil)
+	for i := 0; i < 100; i++ {
+		mg.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mg.AddNode("Extra")
+		mg.RemoveNode("Extra")
+	}
+}
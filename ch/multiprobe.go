@@ -0,0 +1,143 @@
+package ch
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultProbes is the number of probes per lookup used when none is
+// provided, which keeps the peak-to-mean load under about 1.1
+const DefaultProbes = 21
+
+// MultiProbe represents multi-probe consistent hashing of Appleton and
+// O'Reilly. Every node has a single point on a 2^64 ring, and a key is
+// hashed to several probe positions: the node whose point is the closest
+// clockwise to any probe owns the key. More probes trade lookup time for
+// balance, with memory linear in the number of nodes instead of the number
+// of virtual nodes.
+type MultiProbe struct {
+	mu     sync.RWMutex
+	hash   Hash64
+	probes int
+	points []multiProbePoint // Sorted by hash then name
+}
+
+type multiProbePoint struct {
+	hash uint64
+	name string
+}
+
+// NewMultiProbe creates a new multi-probe consistent hashing instance
+// hashing every key probes times, DefaultProbes when zero, with fn,
+// XXHash64 when nil
+func NewMultiProbe(probes int, fn Hash64) (*MultiProbe, error) {
+	if probes == 0 {
+		probes = DefaultProbes
+	}
+	if probes < 0 {
+		return nil, ErrInvalidProbes
+	}
+	if fn == nil {
+		fn = XXHash64
+	}
+	return &MultiProbe{hash: fn, probes: probes}, nil
+}
+
+// AddNode places a node at a single point of the ring
+func (mp *MultiProbe) AddNode(node string) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	for i := range mp.points {
+		if mp.points[i].name == node {
+			return ErrNodeExists
+		}
+	}
+
+	p := multiProbePoint{hash: mp.hash([]byte(node)), name: node}
+	idx := sort.Search(len(mp.points), func(i int) bool {
+		return mp.points[i].hash > p.hash || (mp.points[i].hash == p.hash && mp.points[i].name >= p.name)
+	})
+	mp.points = append(mp.points, multiProbePoint{})
+	copy(mp.points[idx+1:], mp.points[idx:])
+	mp.points[idx] = p
+	return nil
+}
+
+// RemoveNode removes a node from the ring
+func (mp *MultiProbe) RemoveNode(node string) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	for i := range mp.points {
+		if mp.points[i].name == node {
+			mp.points = append(mp.points[:i], mp.points[i+1:]...)
+			return
+		}
+	}
+}
+
+// Nodes returns the nodes sorted by name
+func (mp *MultiProbe) Nodes() []string {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	nodes := make([]string, len(mp.points))
+	for i := range mp.points {
+		nodes[i] = mp.points[i].name
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Probes returns the number of probes per lookup
+func (mp *MultiProbe) Probes() int {
+	return mp.probes
+}
+
+// GetNode returns the node owning the provided key
+func (mp *MultiProbe) GetNode(key string) string {
+	return mp.GetNodeBytes([]byte(key))
+}
+
+// GetNodeBytes returns the node owning a key given as bytes
+func (mp *MultiProbe) GetNodeBytes(key []byte) string {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	if len(mp.points) == 0 {
+		return ""
+	}
+
+	hash := mp.hash(key)
+	best, distance := 0, uint64(0)
+	for i := 0; i < mp.probes; i++ {
+		// Probe i is the key hash mixed with the probe number, so every probe
+		// lands at an independent position
+		probe := fmix64(hash + uint64(i)*0x9e3779b97f4a7c15)
+		idx := mp.successor(probe)
+		// The distance wraps past zero like the ring
+		if d := mp.points[idx].hash - probe; i == 0 || d < distance {
+			best, distance = idx, d
+		}
+	}
+	return mp.points[best].name
+}
+
+// successor returns the index of the first point at or after the position,
+// wrapping to the first point. Callers must hold the lock.
+func (mp *MultiProbe) successor(hash uint64) int {
+	lo, hi := 0, len(mp.points)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if mp.points[mid].hash < hash {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if lo == len(mp.points) {
+		return 0
+	}
+	return lo
+}
@@ -0,0 +1,19 @@
+package ch
+
+import (
+	"fmt"
+	"log"
+)
+
+func ExampleNewMultiProbe() {
+	mp, err := NewMultiProbe(DefaultProbes, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mp.AddNode("10.0.0.1:80")
+	mp.AddNode("10.0.0.2:80")
+	mp.AddNode("10.0.0.3:80")
+
+	fmt.Println("Backend:", mp.GetNode("198.51.100.7:51234"))
+}
@@ -0,0 +1,143 @@
+package ch
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestMultiProbe_InvalidProbes(t *testing.T) {
+	if _, err := NewMultiProbe(-1, nil); err != ErrInvalidProbes {
+		t.Errorf("Expected %v, got %v", ErrInvalidProbes, err)
+	}
+
+	mp, err := NewMultiProbe(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mp.Probes() != DefaultProbes {
+		t.Errorf("Expected %d probes, got %d", DefaultProbes, mp.Probes())
+	}
+}
+
+func TestMultiProbe_GetNode(t *testing.T) {
+	mp, _ := NewMultiProbe(0, nil)
+	if node := mp.GetNode("key"); node != "" {
+		t.Errorf("Expected empty string without nodes, got %s", node)
+	}
+
+	mp.AddNode("NodeA")
+	if node := mp.GetNode("key"); node != "NodeA" {
+		t.Errorf("Expected the single node, got %s", node)
+	}
+
+	mp.AddNode("NodeB")
+	mp.AddNode("NodeC")
+	node := mp.GetNode("my-key")
+	if again := mp.GetNodeBytes([]byte("my-key")); again != node {
+		t.Errorf("Expected consistent mapping, but got %s and %s", node, again)
+	}
+}
+
+// TestMultiProbe_Balance checks that more probes even out the load of nodes
+// that each own a single point
+func TestMultiProbe_Balance(t *testing.T) {
+	const nodes, keys = 50, 200000
+
+	peaks := make(map[int]float64)
+	for _, probes := range []int{1, 5, DefaultProbes} {
+		mp, _ := NewMultiProbe(probes, nil)
+		for i := 0; i < nodes; i++ {
+			mp.AddNode("Node" + strconv.Itoa(i))
+		}
+		_, counts := ownerCounts(mp, keys)
+		peaks[probes] = peakToMean(counts, keys)
+	}
+
+	if peaks[1] < 2 {
+		t.Errorf("Expected a single probe to be as uneven as one point per node, got %.3f", peaks[1])
+	}
+	if peaks[5] >= peaks[1] || peaks[DefaultProbes] >= peaks[5] {
+		t.Errorf("Expected the balance to improve with probes, got %v", peaks)
+	}
+	if peaks[DefaultProbes] > 1.15 {
+		t.Errorf("Expected a peak-to-mean load under 1.15, got %.3f", peaks[DefaultProbes])
+	}
+}
+
+func TestMultiProbe_GetNodeAllocs(t *testing.T) {
+	mp, _ := NewMultiProbe(0, nil)
+	for i := 0; i < 10; i++ {
+		mp.AddNode("Node" + strconv.Itoa(i))
+	}
+	key := []byte("key")
+	if allocs := testing.AllocsPerRun(100, func() { _ = mp.GetNodeBytes(key) }); allocs != 0 {
+		t.Errorf("Expected no allocations, got %.1f", allocs)
+	}
+}
+
+func BenchmarkMultiProbe_GetNode(b *testing.B) {
+	mp, _ := NewMultiProbe(DefaultProbes, nil)
+	for i := 0; i < 100; i++ {
+		mp.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	keys := make([][]byte, 1024)
+	for i := range keys {
+		keys[i] = []byte("key" + strconv.Itoa(i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = mp.GetNodeBytes(keys[i%len(keys)])
+	}
+}
+
+// BenchmarkMultiProbe_VersusMap compares lookups against a Map with the
+// fewest replicas reaching the same peak-to-mean load, and reports the ring
+// points each keeps in memory
+func BenchmarkMultiProbe_VersusMap(b *testing.B) {
+	const nodes, keys = 50, 500000
+
+	mp, _ := NewMultiProbe(DefaultProbes, nil)
+	for i := 0; i < nodes; i++ {
+		mp.AddNode("Node" + strconv.Itoa(i))
+	}
+	_, counts := ownerCounts(mp, keys)
+	peak := peakToMean(counts, keys)
+
+	var m *Map[string]
+	replicas := 0
+	for replicas < 10000 {
+		replicas += 50
+		m = New64[string](replicas, nil)
+		for i := 0; i < nodes; i++ {
+			m.AddNode("Node" + strconv.Itoa(i))
+		}
+		if m.Stats().PeakToMean <= peak {
+			break
+		}
+	}
+
+	lookups := make([][]byte, 1024)
+	for i := range lookups {
+		lookups[i] = []byte("key" + strconv.Itoa(i))
+	}
+
+	b.Run("MultiProbe", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = mp.GetNodeBytes(lookups[i%len(lookups)])
+		}
+		b.ReportMetric(peak, "peak/mean")
+		b.ReportMetric(nodes, "points")
+	})
+	b.Run("Map", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = m.GetNodeBytes(lookups[i%len(lookups)])
+		}
+		b.ReportMetric(m.Stats().PeakToMean, "peak/mean")
+		b.ReportMetric(float64(nodes*replicas), "points")
+	})
+}
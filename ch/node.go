@@ -0,0 +1,195 @@
+package ch
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Node is a ring member with a stable identity, such as a struct carrying an
+// address and metadata. Nodes are placed on the ring by ID, so two values
+// with the same ID are the same node.
+type Node interface {
+	ID() string
+}
+
+// NodeMap is a consistent hash ring of typed nodes. It places the nodes on a
+// Map by ID and returns the node values from lookups, which read a
+// copy-on-write index of the nodes without taking a lock.
+type NodeMap[N Node, T any] struct {
+	mu    sync.Mutex // Serializes membership changes
+	ring  *Map[T]
+	nodes atomic.Pointer[map[string]N] // Node ID -> Node
+}
+
+// NewNodeMap creates a ring of typed nodes on top of an empty ring created by
+// New, New64 or NewWithHash
+func NewNodeMap[N Node, T any](ring *Map[T]) *NodeMap[N, T] {
+	m := &NodeMap[N, T]{ring: ring}
+	m.nodes.Store(&map[string]N{})
+	return m
+}
+
+// Ring returns the underlying ring, keyed by node ID, for features such as
+// stored keys, events and statistics. Membership must only change through
+// the NodeMap, or lookups will not find the values of the nodes.
+func (m *NodeMap[N, T]) Ring() *Map[T] {
+	return m.ring
+}
+
+// AddNode adds a node with weight 1 to the hash ring
+func (m *NodeMap[N, T]) AddNode(node N) error {
+	return m.AddNodeWithWeight(node, 1)
+}
+
+// AddNodeWithWeight adds a node owning weight times as many virtual nodes as
+// a node added with AddNode
+func (m *NodeMap[N, T]) AddNodeWithWeight(node N, weight int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The node is indexed before it reaches the ring, so lookups always find
+	// the value of the owner they get
+	nodes := *m.nodes.Load()
+	if _, ok := nodes[node.ID()]; ok {
+		return ErrNodeExists
+	}
+	m.store(nodes, []N{node}, nil)
+	if err := m.ring.AddNodeWithWeight(node.ID(), weight); err != nil {
+		m.nodes.Store(&nodes)
+		return err
+	}
+	return nil
+}
+
+// AddNodes adds nodes with weight 1 to the hash ring in a single update.
+// Nothing is added when a node is already on the ring or listed twice.
+func (m *NodeMap[N, T]) AddNodes(nodes ...N) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := *m.nodes.Load()
+	ids := make([]string, len(nodes))
+	for i, node := range nodes {
+		if _, ok := old[node.ID()]; ok {
+			return ErrNodeExists
+		}
+		ids[i] = node.ID()
+	}
+
+	m.store(old, nodes, nil)
+	if err := m.ring.AddNodes(ids...); err != nil {
+		m.nodes.Store(&old)
+		return err
+	}
+	return nil
+}
+
+// SetNodes makes the ring membership match the nodes, each with weight 1, in
+// a single update. Nodes already on the ring keep their place and take the
+// new value.
+func (m *NodeMap[N, T]) SetNodes(nodes ...N) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	weights := make(map[string]int, len(nodes))
+	for _, node := range nodes {
+		if _, ok := weights[node.ID()]; ok {
+			return ErrNodeExists
+		}
+		weights[node.ID()] = 1
+	}
+
+	// Lookups keep finding the leaving nodes until the ring drops them
+	old := *m.nodes.Load()
+	m.store(old, nodes, nil)
+	if err := m.ring.SetNodes(weights); err != nil {
+		m.nodes.Store(&old)
+		return err
+	}
+
+	var remove []string
+	for id := range old {
+		if _, ok := weights[id]; !ok {
+			remove = append(remove, id)
+		}
+	}
+	m.store(*m.nodes.Load(), nil, remove)
+	return nil
+}
+
+// RemoveNode removes the node with the ID of the given one from the hash ring
+func (m *NodeMap[N, T]) RemoveNode(node N) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The node leaves the ring before the index, the reverse of AddNode
+	m.ring.RemoveNode(node.ID())
+	m.store(*m.nodes.Load(), nil, []string{node.ID()})
+}
+
+// store publishes a copy of the node index with the nodes added and the ids
+// removed. Callers must hold the lock.
+func (m *NodeMap[N, T]) store(nodes map[string]N, add []N, remove []string) {
+	index := make(map[string]N, len(nodes)+len(add))
+	for id, node := range nodes {
+		index[id] = node
+	}
+	for _, node := range add {
+		index[node.ID()] = node
+	}
+	for _, id := range remove {
+		delete(index, id)
+	}
+	m.nodes.Store(&index)
+}
+
+// Node returns the node with the ID
+func (m *NodeMap[N, T]) Node(id string) (N, bool) {
+	node, ok := (*m.nodes.Load())[id]
+	return node, ok
+}
+
+// Nodes returns the nodes of the hash ring sorted by ID
+func (m *NodeMap[N, T]) Nodes() []N {
+	index := *m.nodes.Load()
+	nodes := make([]N, 0, len(index))
+	for _, node := range index {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID() < nodes[j].ID()
+	})
+	return nodes
+}
+
+// GetNode returns the closest node for the provided key, false when the ring
+// is empty
+func (m *NodeMap[N, T]) GetNode(key string) (N, bool) {
+	return m.Node(m.ring.GetNode(key))
+}
+
+// GetNodeBytes returns the closest node for a key given as bytes
+func (m *NodeMap[N, T]) GetNodeBytes(key []byte) (N, bool) {
+	return m.Node(m.ring.GetNodeBytes(key))
+}
+
+// GetNodeUint64 returns the closest node for a numeric key
+func (m *NodeMap[N, T]) GetNodeUint64(key uint64) (N, bool) {
+	return m.Node(m.ring.GetNodeUint64(key))
+}
+
+// GetNodes returns the owner of the key followed by the next n-1 distinct
+// nodes clockwise on the ring, in preference order
+func (m *NodeMap[N, T]) GetNodes(key string, n int) ([]N, error) {
+	ids, err := m.ring.GetNodes(key, n)
+	if err != nil {
+		return nil, err
+	}
+	index := *m.nodes.Load()
+	nodes := make([]N, len(ids))
+	for i, id := range ids {
+		nodes[i] = index[id]
+	}
+	return nodes, nil
+}
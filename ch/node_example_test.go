@@ -0,0 +1,22 @@
+package ch
+
+import "fmt"
+
+type server struct {
+	Addr string
+	Zone string
+}
+
+func (s server) ID() string {
+	return s.Addr
+}
+
+func ExampleNewNodeMap() {
+	servers := NewNodeMap[server](New[string](100, nil))
+	_ = servers.AddNode(server{Addr: "10.0.0.1:80", Zone: "eu-west-1a"})
+	_ = servers.AddNode(server{Addr: "10.0.0.2:80", Zone: "eu-west-1b"})
+
+	if s, ok := servers.GetNodeUint64(1234); ok {
+		fmt.Println("Server:", s.Addr, s.Zone)
+	}
+}
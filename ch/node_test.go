@@ -0,0 +1,147 @@
+package ch
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+type testServer struct {
+	Addr string
+	Zone string
+}
+
+func (s testServer) ID() string {
+	return s.Addr
+}
+
+func newTestNodeMap(t *testing.T, count int) *NodeMap[testServer, string] {
+	t.Helper()
+	m := NewNodeMap[testServer](New[string](50, nil))
+	for i := 0; i < count; i++ {
+		server := testServer{Addr: "10.0.0." + strconv.Itoa(i), Zone: "zone" + strconv.Itoa(i%2)}
+		if err := m.AddNode(server); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestNodeMap_GetNode(t *testing.T) {
+	m := newTestNodeMap(t, 5)
+
+	for i := 0; i < 100; i++ {
+		key := "key" + strconv.Itoa(i)
+		server, ok := m.GetNode(key)
+		if !ok || server.Addr != m.Ring().GetNode(key) || server.Zone == "" {
+			t.Fatalf("Expected the node value of %s, got %v", m.Ring().GetNode(key), server)
+		}
+		if bytes, _ := m.GetNodeBytes([]byte(key)); bytes != server {
+			t.Fatalf("Expected %v for the bytes of %s, got %v", server, key, bytes)
+		}
+	}
+
+	empty := NewNodeMap[testServer](New[string](50, nil))
+	if _, ok := empty.GetNode("key"); ok {
+		t.Error("Expected no node on an empty ring")
+	}
+}
+
+func TestNodeMap_Membership(t *testing.T) {
+	m := newTestNodeMap(t, 3)
+
+	if err := m.AddNode(testServer{Addr: "10.0.0.1", Zone: "other"}); err != ErrNodeExists {
+		t.Errorf("Expected %v, got %v", ErrNodeExists, err)
+	}
+	if server, _ := m.Node("10.0.0.1"); server.Zone != "zone1" {
+		t.Errorf("Expected a failed add to keep the node value, got %v", server)
+	}
+	if err := m.AddNodes(testServer{Addr: "10.0.0.9"}, testServer{Addr: "10.0.0.2"}); err != ErrNodeExists {
+		t.Errorf("Expected %v, got %v", ErrNodeExists, err)
+	}
+	if err := m.AddNodeWithWeight(testServer{Addr: "10.0.0.9"}, 0); err != ErrInvalidWeight {
+		t.Errorf("Expected %v, got %v", ErrInvalidWeight, err)
+	}
+	if _, ok := m.Node("10.0.0.9"); ok {
+		t.Error("Expected failed adds to leave no node behind")
+	}
+
+	m.RemoveNode(testServer{Addr: "10.0.0.0"})
+	nodes := m.Nodes()
+	if len(nodes) != 2 || nodes[0].Addr != "10.0.0.1" || nodes[1].Addr != "10.0.0.2" {
+		t.Fatalf("Expected the remaining nodes sorted by ID, got %v", nodes)
+	}
+	if ids := m.Ring().Nodes(); len(ids) != 2 {
+		t.Errorf("Expected the ring to follow, got %v", ids)
+	}
+
+	replicas, err := m.GetNodes("key", 2)
+	if err != nil || len(replicas) != 2 || replicas[0] == replicas[1] {
+		t.Errorf("Expected two distinct replicas, got %v, %v", replicas, err)
+	}
+}
+
+func TestNodeMap_SetNodes(t *testing.T) {
+	m := newTestNodeMap(t, 3)
+
+	err := m.SetNodes(testServer{Addr: "10.0.0.1", Zone: "moved"}, testServer{Addr: "10.0.0.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := m.Nodes()
+	if len(nodes) != 2 || nodes[0].Zone != "moved" || nodes[1].Addr != "10.0.0.5" {
+		t.Fatalf("Expected the new membership with updated values, got %v", nodes)
+	}
+	if ids := m.Ring().Nodes(); len(ids) != 2 || ids[0] != "10.0.0.1" || ids[1] != "10.0.0.5" {
+		t.Errorf("Expected the ring to follow, got %v", ids)
+	}
+
+	if err := m.SetNodes(testServer{Addr: "10.0.0.7"}, testServer{Addr: "10.0.0.7"}); err != ErrNodeExists {
+		t.Errorf("Expected %v, got %v", ErrNodeExists, err)
+	}
+	if len(m.Nodes()) != 2 {
+		t.Errorf("Expected a failed update to change nothing, got %v", m.Nodes())
+	}
+}
+
+func TestNodeMap_NoAllocations(t *testing.T) {
+	m := newTestNodeMap(t, 5)
+	key := []byte("key")
+
+	if allocs := testing.AllocsPerRun(100, func() { m.GetNodeBytes(key) }); allocs != 0 {
+		t.Errorf("Expected no allocations for a bytes key, got %v", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { m.GetNodeUint64(42) }); allocs != 0 {
+		t.Errorf("Expected no allocations for a uint64 key, got %v", allocs)
+	}
+}
+
+func TestConsistentHashing_GetNodeUint64(t *testing.T) {
+	for _, ch := range []*Map[string]{New[string](100, nil), New64[string](100, nil)} {
+		_ = ch.AddNodes("NodeA", "NodeB", "NodeC", "NodeD")
+
+		// Sequential ids land on each node in proportion to the arcs it owns
+		counts := make(map[string]int)
+		for i := uint64(0); i < 10000; i++ {
+			counts[ch.GetNodeUint64(i)]++
+		}
+		for node, share := range ch.Stats().Ownership {
+			if got := float64(counts[node]) / 10000; math.Abs(got-share) > 0.03 {
+				t.Errorf("Expected %s to get %.3f of the ids, got %.3f", node, share, got)
+			}
+		}
+	}
+}
+
+func BenchmarkNodeMap_GetNodeUint64(b *testing.B) {
+	m := NewNodeMap[testServer](New[string](100, nil))
+	for i := 0; i < 100; i++ {
+		_ = m.AddNode(testServer{Addr: "10.0.0." + strconv.Itoa(i)})
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.GetNodeUint64(uint64(i))
+	}
+}
@@ -0,0 +1,80 @@
+package ch
+
+import "sort"
+
+// KeysForNode returns the stored keys owned by the node, sorted
+func (m *Map[T]) KeysForNode(node string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]string, 0, len(m.partitions[node]))
+	for key := range m.partitions[node] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// KeyCount returns the number of stored keys owned by the node
+func (m *Map[T]) KeyCount(node string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.partitions[node])
+}
+
+// KeyCounts returns the number of stored keys owned by every node
+func (m *Map[T]) KeyCounts() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int, len(m.weights))
+	for node := range m.weights {
+		counts[node] = len(m.partitions[node])
+	}
+	return counts
+}
+
+// SetEvictionHook registers a function called with the partition of a node
+// after the node is removed, nil to unregister. The keys stay stored and are
+// already owned by their new nodes when the hook runs, which makes it the
+// place to hand the data off. The hook runs without the lock held.
+func (m *Map[T]) SetEvictionHook(fn func(node string, data map[string]T)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.evictionHook = fn
+}
+
+// assign adds a stored key to the partition of a node. Keys left without a
+// node when the ring empties wait in the partition of the empty node name.
+// Callers must hold the lock.
+func (m *Map[T]) assign(key, node string) {
+	partition, ok := m.partitions[node]
+	if !ok {
+		partition = make(map[string]struct{})
+		m.partitions[node] = partition
+	}
+	partition[key] = struct{}{}
+	m.owners[key] = node
+}
+
+// unassign removes a stored key from the partition of its node.
+// Callers must hold the lock.
+func (m *Map[T]) unassign(key string) {
+	node := m.owners[key]
+	delete(m.partitions[node], key)
+	if len(m.partitions[node]) == 0 {
+		delete(m.partitions, node)
+	}
+	delete(m.owners, key)
+}
+
+// repartition moves every stored key whose ring owner changed to the
+// partition of its new owner. Callers must hold the lock.
+func (m *Map[T]) repartition() {
+	for key, node := range m.owners {
+		if owner := m.owner(key); owner != node {
+			m.unassign(key)
+			m.assign(key, owner)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package ch
+
+import (
+	"strconv"
+	"testing"
+)
+
+func checkPartitions[T any](t *testing.T, ch *Map[T]) {
+	t.Helper()
+	total := 0
+	for node, keys := range ch.partitions {
+		for key := range keys {
+			if owner := ch.GetNode(key); owner != node {
+				t.Fatalf("Key %s in partition %q, expected %q", key, node, owner)
+			}
+		}
+		total += len(keys)
+	}
+	if total != len(ch.data) {
+		t.Fatalf("Expected %d partitioned keys, got %d", len(ch.data), total)
+	}
+}
+
+func TestPartition_KeysForNode(t *testing.T) {
+	ch := New[int](20, nil)
+	_ = ch.AddNode("NodeA")
+	_ = ch.AddNode("NodeB")
+	for i := 0; i < 100; i++ {
+		ch.AddKey("key"+strconv.Itoa(i), i)
+	}
+	checkPartitions(t, ch)
+
+	counts := ch.KeyCounts()
+	if counts["NodeA"]+counts["NodeB"] != 100 {
+		t.Errorf("Expected 100 keys across both nodes, got %v", counts)
+	}
+	keys := ch.KeysForNode("NodeA")
+	if len(keys) != ch.KeyCount("NodeA") {
+		t.Errorf("Expected %d keys, got %d", ch.KeyCount("NodeA"), len(keys))
+	}
+	for i, key := range keys {
+		if ch.GetNode(key) != "NodeA" {
+			t.Errorf("Key %s is not owned by NodeA", key)
+		}
+		if i > 0 && keys[i-1] >= key {
+			t.Errorf("Expected sorted keys")
+		}
+	}
+
+	ch.AddKey("key0", 1000)
+	ch.RemoveKey("key1")
+	ch.RemoveKey("missing")
+	checkPartitions(t, ch)
+	if ch.KeyCount("NodeA")+ch.KeyCount("NodeB") != 99 {
+		t.Errorf("Expected 99 keys after removing one")
+	}
+}
+
+func TestPartition_MembershipChanges(t *testing.T) {
+	ch := New[int](20, nil)
+	_ = ch.AddNode("NodeA")
+	for i := 0; i < 500; i++ {
+		ch.AddKey("key"+strconv.Itoa(i), i)
+	}
+	if ch.KeyCount("NodeA") != 500 {
+		t.Fatalf("Expected NodeA to own every key, got %d", ch.KeyCount("NodeA"))
+	}
+
+	_ = ch.AddNode("NodeB")
+	checkPartitions(t, ch)
+	if ch.KeyCount("NodeB") == 0 {
+		t.Error("Expected NodeB to take over keys")
+	}
+
+	_ = ch.AddNodeWithWeight("NodeC", 3)
+	_ = ch.SetWeight("NodeB", 2)
+	checkPartitions(t, ch)
+
+	ch.RemoveNode("NodeC")
+	checkPartitions(t, ch)
+	if ch.KeyCount("NodeC") != 0 {
+		t.Error("Expected the removed node to own no keys")
+	}
+}
+
+func TestPartition_EvictionHook(t *testing.T) {
+	ch := New[int](20, nil)
+	_ = ch.AddNode("NodeA")
+	_ = ch.AddNode("NodeB")
+	for i := 0; i < 200; i++ {
+		ch.AddKey("key"+strconv.Itoa(i), i)
+	}
+	owned := ch.KeysForNode("NodeB")
+
+	var evictedNode string
+	var evicted map[string]int
+	ch.SetEvictionHook(func(node string, data map[string]int) {
+		evictedNode, evicted = node, data
+		// The hook runs without the lock, the map can be used
+		if owner := ch.GetNode("key0"); owner != "NodeA" {
+			t.Errorf("Expected NodeA to own every key, got %s", owner)
+		}
+	})
+
+	ch.RemoveNode("Missing")
+	if evicted != nil {
+		t.Fatal("Expected no eviction for a missing node")
+	}
+
+	ch.RemoveNode("NodeB")
+	if evictedNode != "NodeB" || len(evicted) != len(owned) {
+		t.Fatalf("Expected %d evicted keys of NodeB, got %d of %q", len(owned), len(evicted), evictedNode)
+	}
+	for _, key := range owned {
+		value, ok := ch.GetKey(key)
+		if evicted[key] != value || !ok {
+			t.Errorf("Expected evicted %s to carry %d", key, value)
+		}
+	}
+	if ch.KeyCount("NodeA") != 200 {
+		t.Errorf("Expected NodeA to own every key, got %d", ch.KeyCount("NodeA"))
+	}
+}
+
+func TestPartition_EmptyRing(t *testing.T) {
+	ch := New[int](20, nil)
+	_ = ch.AddNode("NodeA")
+	ch.AddKey("key", 1)
+	ch.RemoveNode("NodeA")
+
+	if _, ok := ch.GetKey("key"); !ok {
+		t.Fatal("Expected the key to stay stored")
+	}
+	_ = ch.AddNode("NodeB")
+	if keys := ch.KeysForNode("NodeB"); len(keys) != 1 || keys[0] != "key" {
+		t.Errorf("Expected NodeB to own the waiting key, got %v", keys)
+	}
+}
@@ -0,0 +1,8 @@
+package proxy
+
+import "errors"
+
+var (
+	ErrInvalidBackend = errors.New("backend must be an absolute URL with a host")
+	ErrNoBackend      = errors.New("no backend available for the request")
+)
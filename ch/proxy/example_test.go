@@ -0,0 +1,23 @@
+package proxy
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+func ExampleNew() {
+	p, err := New(Config{
+		Key:       FirstOf(Header("X-User-ID"), Cookie("session")),
+		MaxErrors: 5,
+		Cooldown:  30 * time.Second,
+	}, "http://10.0.0.1:8080", "http://10.0.0.2:8080")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Backends can change while serving, e.g. from service discovery
+	_ = p.SetBackends("http://10.0.0.1:8080", "http://10.0.0.3:8080")
+
+	http.Handle("/", p)
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// KeyFunc extracts the sticky key of a request. Requests with the same key
+// go to the same backend. ok is false when the request carries no key.
+type KeyFunc func(r *http.Request) (key string, ok bool)
+
+// Header keys requests by the value of a header
+func Header(name string) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		value := r.Header.Get(name)
+		return value, value != ""
+	}
+}
+
+// Cookie keys requests by the value of a cookie
+func Cookie(name string) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		c, err := r.Cookie(name)
+		if err != nil || c.Value == "" {
+			return "", false
+		}
+		return c.Value, true
+	}
+}
+
+// PathSegment keys requests by the i-th segment of the URL path, counted from
+// zero, so PathSegment(1) keys "/users/42/orders" by "42"
+func PathSegment(i int) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if i < 0 || i >= len(segments) || segments[i] == "" {
+			return "", false
+		}
+		return segments[i], true
+	}
+}
+
+// ClientIP keys requests by the IP address of the client connection
+func ClientIP() KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		return host, host != ""
+	}
+}
+
+// FirstOf keys requests by the first extractor that finds a key
+func FirstOf(fns ...KeyFunc) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		for _, fn := range fns {
+			if key, ok := fn(r); ok {
+				return key, true
+			}
+		}
+		return "", false
+	}
+}
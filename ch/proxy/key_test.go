@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKeyFuncs(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/users/42/orders", nil)
+	r.RemoteAddr = "198.51.100.7:51234"
+	r.Header.Set("X-User", "alice")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "s3cr3t"})
+
+	tests := []struct {
+		name string
+		fn   KeyFunc
+		key  string
+		ok   bool
+	}{
+		{"Header", Header("X-User"), "alice", true},
+		{"MissingHeader", Header("X-Tenant"), "", false},
+		{"Cookie", Cookie("session"), "s3cr3t", true},
+		{"MissingCookie", Cookie("other"), "", false},
+		{"PathSegment", PathSegment(1), "42", true},
+		{"FirstPathSegment", PathSegment(0), "users", true},
+		{"PathSegmentOutOfRange", PathSegment(3), "", false},
+		{"ClientIP", ClientIP(), "198.51.100.7", true},
+		{"FirstOf", FirstOf(Header("X-Tenant"), Cookie("session")), "s3cr3t", true},
+		{"FirstOfNone", FirstOf(Header("X-Tenant"), Cookie("other")), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ok := tt.fn(r)
+			if key != tt.key || ok != tt.ok {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.key, tt.ok, key, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ja7ad/algo/ch"
+)
+
+// Defaults of a Proxy
+const (
+	DefaultReplicas = 100
+	DefaultCooldown = 10 * time.Second
+)
+
+// Config configures a Proxy
+type Config struct {
+	// Key extracts the sticky key of a request. Requests without a key, and
+	// every request when Key is nil, are keyed by the client IP.
+	Key KeyFunc
+	// Replicas is the number of virtual nodes per backend, DefaultReplicas
+	// when zero
+	Replicas int
+	// MaxErrors is the number of consecutive failures, transport errors or 5xx
+	// responses, after which a backend is marked down. Zero disables ejection.
+	MaxErrors int
+	// Cooldown is how long an ejected backend stays down before it is tried
+	// again, DefaultCooldown when zero
+	Cooldown time.Duration
+	// Transport sends the proxied requests, http.DefaultTransport when nil
+	Transport http.RoundTripper
+	// ErrorLog logs proxy errors, the standard logger when nil
+	ErrorLog *log.Logger
+}
+
+// Proxy is a reverse proxy sending every request to the backend owning its
+// sticky key on a consistent hash ring. Backends that keep failing are
+// marked down on the ring, so their keys fall through to the next backend
+// and return to them exactly once they recover.
+type Proxy struct {
+	ring      *ch.NodeMap[*backend, struct{}]
+	key       KeyFunc
+	proxy     *httputil.ReverseProxy
+	maxErrors int
+	cooldown  time.Duration
+
+	mu sync.Mutex // Guards the failure counts and cooldowns of the backends
+}
+
+// backend is a ring node forwarding to a URL
+type backend struct {
+	id     string
+	url    *url.URL
+	errors int         // Consecutive failures
+	down   bool        // Ejected until the cooldown ends
+	timer  *time.Timer // Ends the cooldown, nil once it ended or was stopped
+}
+
+func (b *backend) ID() string {
+	return b.id
+}
+
+// backendKey is the context key of the backend picked for a request
+type backendKey struct{}
+
+// New creates a proxy balancing across the backends, given as absolute URLs
+func New(cfg Config, backends ...string) (*Proxy, error) {
+	if cfg.Replicas == 0 {
+		cfg.Replicas = DefaultReplicas
+	}
+	if cfg.Cooldown == 0 {
+		cfg.Cooldown = DefaultCooldown
+	}
+
+	p := &Proxy{
+		ring:      ch.NewNodeMap[*backend](ch.New[struct{}](cfg.Replicas, nil)),
+		key:       cfg.Key,
+		maxErrors: cfg.MaxErrors,
+		cooldown:  cfg.Cooldown,
+	}
+	p.proxy = &httputil.ReverseProxy{
+		Director:       p.direct,
+		Transport:      cfg.Transport,
+		ErrorLog:       cfg.ErrorLog,
+		ModifyResponse: p.modifyResponse,
+		ErrorHandler:   p.handleError,
+	}
+	if err := p.SetBackends(backends...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ServeHTTP proxies the request to the backend owning its key, and answers
+// 503 Service Unavailable when every backend is down
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, ok := p.ring.GetNode(p.requestKey(r))
+	if !ok {
+		http.Error(w, ErrNoBackend.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	p.proxy.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), backendKey{}, b)))
+}
+
+// Backend returns the backend the request would be sent to
+func (p *Proxy) Backend(r *http.Request) (string, error) {
+	b, ok := p.ring.GetNode(p.requestKey(r))
+	if !ok {
+		return "", ErrNoBackend
+	}
+	return b.id, nil
+}
+
+func (p *Proxy) requestKey(r *http.Request) string {
+	if p.key != nil {
+		if key, ok := p.key(r); ok {
+			return key
+		}
+	}
+	key, _ := ClientIP()(r)
+	return key
+}
+
+// AddBackend adds a backend to the ring
+func (p *Proxy) AddBackend(rawURL string) error {
+	b, err := newBackend(rawURL)
+	if err != nil {
+		return err
+	}
+	return p.ring.AddNode(b)
+}
+
+// RemoveBackend removes a backend from the ring and ends its cooldown, so a
+// backend added again under the same URL starts afresh
+func (p *Proxy) RemoveBackend(rawURL string) {
+	b, err := newBackend(rawURL)
+	if err != nil {
+		return
+	}
+	if existing, ok := p.ring.Node(b.id); ok {
+		p.ring.RemoveNode(existing)
+		p.stopCooldown(existing)
+	}
+}
+
+// SetBackends replaces the backends in a single ring update. Backends that
+// stay keep their keys and their failure count.
+func (p *Proxy) SetBackends(rawURLs ...string) error {
+	backends := make([]*backend, len(rawURLs))
+	for i, rawURL := range rawURLs {
+		b, err := newBackend(rawURL)
+		if err != nil {
+			return err
+		}
+		if existing, ok := p.ring.Node(b.id); ok {
+			b = existing
+		}
+		backends[i] = b
+	}
+
+	previous := p.ring.Nodes()
+	if err := p.ring.SetNodes(backends...); err != nil {
+		return err
+	}
+	for _, b := range previous {
+		if current, ok := p.ring.Node(b.id); !ok || current != b {
+			p.stopCooldown(b)
+		}
+	}
+	return nil
+}
+
+// Close stops the cooldown timers of the ejected backends, which then stay
+// down. The proxy must not be used after Close.
+func (p *Proxy) Close() {
+	for _, b := range p.ring.Nodes() {
+		p.stopCooldown(b)
+	}
+}
+
+// Backends returns the URLs of the backends, sorted
+func (p *Proxy) Backends() []string {
+	nodes := p.ring.Nodes()
+	urls := make([]string, len(nodes))
+	for i, b := range nodes {
+		urls[i] = b.id
+	}
+	return urls
+}
+
+// State returns whether a backend is active or ejected
+func (p *Proxy) State(rawURL string) ch.NodeState {
+	b, err := newBackend(rawURL)
+	if err != nil {
+		return ch.StateActive
+	}
+	return p.ring.Ring().State(b.id)
+}
+
+func newBackend(rawURL string) (*backend, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return nil, ErrInvalidBackend
+	}
+	return &backend{id: u.String(), url: u}, nil
+}
+
+// direct points the outgoing request at the backend picked by ServeHTTP
+func (p *Proxy) direct(r *http.Request) {
+	b := r.Context().Value(backendKey{}).(*backend)
+	target := b.url
+
+	r.URL.Scheme = target.Scheme
+	r.URL.Host = target.Host
+	r.URL.Path = joinPath(target.Path, r.URL.Path)
+	r.URL.RawPath = ""
+	if target.RawQuery == "" || r.URL.RawQuery == "" {
+		r.URL.RawQuery = target.RawQuery + r.URL.RawQuery
+	} else {
+		r.URL.RawQuery = target.RawQuery + "&" + r.URL.RawQuery
+	}
+}
+
+func joinPath(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case strings.HasSuffix(a, "/") && strings.HasPrefix(b, "/"):
+		return a + b[1:]
+	case !strings.HasSuffix(a, "/") && !strings.HasPrefix(b, "/"):
+		return a + "/" + b
+	}
+	return a + b
+}
+
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	b := resp.Request.Context().Value(backendKey{}).(*backend)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		p.failure(b)
+	} else {
+		p.success(b)
+	}
+	return nil
+}
+
+func (p *Proxy) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	// A client hanging up says nothing about the health of the backend
+	if !errors.Is(err, context.Canceled) && r.Context().Err() == nil {
+		p.failure(r.Context().Value(backendKey{}).(*backend))
+	}
+	if p.proxy.ErrorLog != nil {
+		p.proxy.ErrorLog.Printf("proxy: %v", err)
+	} else {
+		log.Printf("proxy: %v", err)
+	}
+	w.WriteHeader(http.StatusBadGateway)
+}
+
+// failure counts a failed request and marks the backend down once it
+// reaches MaxErrors consecutive failures
+func (p *Proxy) failure(b *backend) {
+	if p.maxErrors == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b.errors++
+	if b.down || b.errors < p.maxErrors {
+		return
+	}
+	if err := p.ring.Ring().SetState(b.id, ch.StateDown); err != nil {
+		return // Removed meanwhile
+	}
+	b.down = true
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.timer = time.AfterFunc(p.cooldown, func() { p.recover(b) })
+}
+
+func (p *Proxy) success(b *backend) {
+	if p.maxErrors == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !b.down {
+		b.errors = 0
+	}
+}
+
+// recover brings a backend back after its cooldown. It is one failure away
+// from being ejected again until a request succeeds.
+func (p *Proxy) recover(b *backend) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The backend was removed or the proxy closed while the timer fired
+	if b.timer == nil {
+		return
+	}
+	b.timer = nil
+	b.down = false
+	b.errors = p.maxErrors - 1
+	_ = p.ring.Ring().SetState(b.id, ch.StateActive)
+}
+
+// stopCooldown stops the cooldown timer of a backend leaving the ring
+func (p *Proxy) stopCooldown(b *backend) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+}
@@ -0,0 +1,248 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Ja7ad/algo/ch"
+)
+
+// newBackendServer starts a backend answering with its name, or with 500
+// Internal Server Error while failing is set
+func newBackendServer(t *testing.T, name string, failing *atomic.Bool) *httptest.Server {
+	t.Helper()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing != nil && failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = io.WriteString(w, name+" "+r.URL.Path)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// get sends a request for the user through the proxy and returns the
+// status and body
+func get(t *testing.T, p *Proxy, user string) (int, string) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	r.Header.Set("X-User", user)
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	return w.Code, w.Body.String()
+}
+
+func quietLog() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
+
+func TestProxy_Sticky(t *testing.T) {
+	a := newBackendServer(t, "a", nil)
+	b := newBackendServer(t, "b", nil)
+	p, err := New(Config{Key: Header("X-User")}, a.URL, b.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]int)
+	for i := 0; i < 50; i++ {
+		user := "user" + strconv.Itoa(i)
+		code, first := get(t, p, user)
+		if code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", code)
+		}
+		if _, again := get(t, p, user); again != first {
+			t.Fatalf("Expected %s to stick to one backend, got %q and %q", user, first, again)
+		}
+		seen[first]++
+	}
+	if len(seen) != 2 {
+		t.Errorf("Expected requests on both backends, got %v", seen)
+	}
+	if _, body := get(t, p, "user0"); body != "a /api/items" && body != "b /api/items" {
+		t.Errorf("Expected the request path forwarded, got %q", body)
+	}
+}
+
+func TestProxy_Ejection(t *testing.T) {
+	var failing atomic.Bool
+	a := newBackendServer(t, "a", &failing)
+	b := newBackendServer(t, "b", nil)
+	p, err := New(Config{Key: Header("X-User"), MaxErrors: 3, Cooldown: 100 * time.Millisecond}, a.URL, b.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Find a user served by the failing backend
+	user := ""
+	for i := 0; user == ""; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-User", "user"+strconv.Itoa(i))
+		if backend, _ := p.Backend(r); backend == a.URL {
+			user = "user" + strconv.Itoa(i)
+		}
+	}
+
+	failing.Store(true)
+	for i := 0; i < 3; i++ {
+		if code, _ := get(t, p, user); code != http.StatusInternalServerError {
+			t.Fatalf("Expected the backend error passed through, got %d", code)
+		}
+	}
+	if state := p.State(a.URL); state != ch.StateDown {
+		t.Fatalf("Expected the backend down after 3 errors, got %v", state)
+	}
+	if _, body := get(t, p, user); body != "b /api/items" {
+		t.Fatalf("Expected the key to fall through to b, got %q", body)
+	}
+
+	// After the cooldown the key returns to its recovered backend
+	failing.Store(false)
+	time.Sleep(200 * time.Millisecond)
+	if _, body := get(t, p, user); body != "a /api/items" {
+		t.Fatalf("Expected the key back on a, got %q", body)
+	}
+	if state := p.State(a.URL); state != ch.StateActive {
+		t.Errorf("Expected the backend active, got %v", state)
+	}
+}
+
+func TestProxy_ReAddedDuringCooldown(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	a := newBackendServer(t, "a", &failing)
+	p, err := New(Config{MaxErrors: 1, Cooldown: 400 * time.Millisecond}, a.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	get(t, p, "user")
+	if state := p.State(a.URL); state != ch.StateDown {
+		t.Fatalf("Expected the backend down, got %v", state)
+	}
+
+	// Removed and added again, the backend starts afresh and is ejected
+	// halfway through the cooldown of its previous incarnation
+	p.RemoveBackend(a.URL)
+	if err := p.AddBackend(a.URL); err != nil {
+		t.Fatal(err)
+	}
+	if state := p.State(a.URL); state != ch.StateActive {
+		t.Fatalf("Expected the re-added backend active, got %v", state)
+	}
+	time.Sleep(200 * time.Millisecond)
+	get(t, p, "user")
+
+	// The first cooldown ended, but only the second one brings it back
+	time.Sleep(300 * time.Millisecond)
+	if state := p.State(a.URL); state != ch.StateDown {
+		t.Fatalf("Expected the stale cooldown not to recover the backend, got %v", state)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if state := p.State(a.URL); state != ch.StateActive {
+		t.Errorf("Expected the backend recovered after its own cooldown, got %v", state)
+	}
+}
+
+func TestProxy_TransportError(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	p, err := New(Config{MaxErrors: 1, Cooldown: time.Hour, ErrorLog: quietLog()}, dead.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _ := get(t, p, "user"); code != http.StatusBadGateway {
+		t.Fatalf("Expected 502 for an unreachable backend, got %d", code)
+	}
+	if code, _ := get(t, p, "user"); code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 once the only backend is down, got %d", code)
+	}
+}
+
+func TestProxy_ClientCancel(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+	p, err := New(Config{MaxErrors: 2, Cooldown: time.Hour, ErrorLog: quietLog()}, slow.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clients giving up before the backend answers do not count as failures
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		p.ServeHTTP(httptest.NewRecorder(), r)
+		cancel()
+
+		ctx, cancel = context.WithCancel(context.Background())
+		r = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		time.AfterFunc(20*time.Millisecond, cancel)
+		p.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	if state := p.State(slow.URL); state != ch.StateActive {
+		t.Fatalf("Expected the backend active after client cancellations, got %v", state)
+	}
+	if code, _ := get(t, p, "user"); code != http.StatusOK {
+		t.Errorf("Expected 200 from the slow backend, got %d", code)
+	}
+}
+
+func TestProxy_DynamicBackends(t *testing.T) {
+	a := newBackendServer(t, "a", nil)
+	b := newBackendServer(t, "b", nil)
+	c := newBackendServer(t, "c", nil)
+	p, err := New(Config{Key: Header("X-User")}, a.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.AddBackend(b.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddBackend(b.URL); err != ch.ErrNodeExists {
+		t.Errorf("Expected %v, got %v", ch.ErrNodeExists, err)
+	}
+	if err := p.SetBackends(b.URL, c.URL); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{b.URL, c.URL}
+	sort.Strings(want)
+	if got := p.Backends(); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("Expected backends %v, got %v", want, got)
+	}
+	for i := 0; i < 50; i++ {
+		if _, body := get(t, p, "user"+strconv.Itoa(i)); body == "a /api/items" {
+			t.Fatal("Expected no request on the removed backend")
+		}
+	}
+
+	p.RemoveBackend(b.URL)
+	p.RemoveBackend(c.URL)
+	if code, _ := get(t, p, "user"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without backends, got %d", code)
+	}
+}
+
+func TestProxy_InvalidBackend(t *testing.T) {
+	for _, raw := range []string{"localhost:8080", "/path", "http://", "://bad"} {
+		if _, err := New(Config{}, raw); err != ErrInvalidBackend {
+			t.Errorf("Expected %v for %q, got %v", ErrInvalidBackend, raw, err)
+		}
+	}
+}
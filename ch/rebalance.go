@@ -0,0 +1,56 @@
+package ch
+
+import "sort"
+
+// Move describes a stored key whose owner changed with the ring membership
+type Move struct {
+	Key  string
+	From string // Owner before the change, empty when the ring was empty
+	To   string // Owner after the change, empty when the ring is now empty
+}
+
+// Rebalance applies a membership change to the ring and returns, sorted by
+// key, every stored key whose owner changed. The change runs without the
+// lock held, so it may call AddNode, RemoveNode, SetWeight and friends.
+// When the change fails, the moves caused by what it applied are still
+// returned along with its error.
+func (m *Map[T]) Rebalance(change func(m *Map[T]) error) ([]Move, error) {
+	var moves []Move
+	err := m.RebalanceFunc(change, func(move Move) bool {
+		moves = append(moves, move)
+		return true
+	})
+	sort.Slice(moves, func(i, j int) bool {
+		return moves[i].Key < moves[j].Key
+	})
+	return moves, err
+}
+
+// RebalanceFunc works like Rebalance but hands every move to fn instead of
+// returning them, in no particular order. It is not streaming: the moved
+// keys are found under the read lock and buffered, so it needs memory for
+// the moves but not for every stored key. fn runs without the lock held.
+// Returning false from fn stops the iteration, the change itself is always
+// applied.
+func (m *Map[T]) RebalanceFunc(change func(m *Map[T]) error, fn func(move Move) bool) error {
+	before := m.snapshot()
+	err := change(m)
+
+	var moves []Move
+	m.mu.RLock()
+	after := m.snapshot()
+	for key := range m.data {
+		hash := after.hash([]byte(key))
+		if from, to := before.owner(hash), after.owner(hash); from != to {
+			moves = append(moves, Move{Key: key, From: from, To: to})
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, move := range moves {
+		if !fn(move) {
+			break
+		}
+	}
+	return err
+}
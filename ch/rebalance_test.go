@@ -0,0 +1,147 @@
+package ch
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func newRebalanceMap(nodes, keys int) *Map[int] {
+	ch := New[int](50, nil)
+	for i := 0; i < nodes; i++ {
+		_ = ch.AddNode("Node" + strconv.Itoa(i))
+	}
+	for i := 0; i < keys; i++ {
+		ch.AddKey("key"+strconv.Itoa(i), i)
+	}
+	return ch
+}
+
+func TestRebalance_AddNode(t *testing.T) {
+	ch := newRebalanceMap(4, 5000)
+	before := make(map[string]string)
+	for i := 0; i < 5000; i++ {
+		key := "key" + strconv.Itoa(i)
+		before[key] = ch.GetNode(key)
+	}
+
+	moves, err := ch.Rebalance(func(m *Map[int]) error {
+		return m.AddNode("Node4")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) == 0 {
+		t.Fatal("Expected keys to move to the new node")
+	}
+
+	moved := 0
+	for key, owner := range before {
+		if ch.GetNode(key) != owner {
+			moved++
+		}
+	}
+	if moved != len(moves) {
+		t.Errorf("Expected %d moves, got %d", moved, len(moves))
+	}
+	for i, move := range moves {
+		if move.To != "Node4" || move.From != before[move.Key] {
+			t.Fatalf("Unexpected move %+v", move)
+		}
+		if i > 0 && moves[i-1].Key >= move.Key {
+			t.Fatalf("Expected moves sorted by key")
+		}
+	}
+}
+
+func TestRebalance_RemoveNode(t *testing.T) {
+	ch := newRebalanceMap(4, 5000)
+	moves, _ := ch.Rebalance(func(m *Map[int]) error {
+		m.RemoveNode("Node2")
+		return nil
+	})
+
+	owned := 0
+	for i := 0; i < 5000; i++ {
+		if ch.GetNode("key"+strconv.Itoa(i)) == "Node2" {
+			t.Fatal("Key still mapped to the removed node")
+		}
+	}
+	for _, move := range moves {
+		if move.From != "Node2" || move.To != ch.GetNode(move.Key) {
+			t.Fatalf("Unexpected move %+v", move)
+		}
+		owned++
+	}
+	if owned == 0 {
+		t.Error("Expected the keys of the removed node to move")
+	}
+}
+
+func TestRebalance_ChangeError(t *testing.T) {
+	ch := newRebalanceMap(2, 1000)
+	failure := errors.New("failure")
+
+	moves, err := ch.Rebalance(func(m *Map[int]) error {
+		_ = m.AddNode("Node2")
+		return failure
+	})
+	if err != failure {
+		t.Errorf("Expected %v, got %v", failure, err)
+	}
+	if len(moves) == 0 {
+		t.Error("Expected the moves of the applied part of the change")
+	}
+}
+
+func TestRebalance_EmptyRing(t *testing.T) {
+	ch := newRebalanceMap(1, 100)
+	moves, _ := ch.Rebalance(func(m *Map[int]) error {
+		m.RemoveNode("Node0")
+		return nil
+	})
+	if len(moves) != 100 {
+		t.Fatalf("Expected all 100 keys to lose their owner, got %d", len(moves))
+	}
+	for _, move := range moves {
+		if move.From != "Node0" || move.To != "" {
+			t.Fatalf("Unexpected move %+v", move)
+		}
+	}
+}
+
+func TestRebalanceFunc_Stop(t *testing.T) {
+	ch := newRebalanceMap(4, 5000)
+	seen := 0
+	err := ch.RebalanceFunc(func(m *Map[int]) error {
+		return m.SetWeight("Node0", 3)
+	}, func(move Move) bool {
+		seen++
+		return seen < 10
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen != 10 {
+		t.Errorf("Expected the stream to stop after 10 moves, got %d", seen)
+	}
+	if ch.Weight("Node0") != 3 {
+		t.Errorf("Expected the change to be applied")
+	}
+}
+
+func BenchmarkRebalanceFunc(b *testing.B) {
+	ch := newRebalanceMap(100, 100000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ch.RebalanceFunc(func(m *Map[int]) error {
+			if i%2 == 0 {
+				return m.AddNode("Extra")
+			}
+			m.RemoveNode("Extra")
+			return nil
+		}, func(Move) bool { return true })
+	}
+}
@@ -0,0 +1,178 @@
+package ch
+
+import (
+	"hash/crc32"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Rendezvous represents rendezvous (highest random weight) hashing. Every node
+// scores every key and the highest score wins, so no virtual nodes are needed
+// and the distribution follows the weights exactly in expectation.
+type Rendezvous struct {
+	mu    sync.RWMutex
+	hash  Hash
+	nodes []rendezvousNode // Sorted by name
+}
+
+type rendezvousNode struct {
+	name   string
+	hash   uint32
+	weight int
+}
+
+// NewRendezvous creates a new rendezvous hashing instance
+func NewRendezvous(fn Hash) *Rendezvous {
+	r := &Rendezvous{hash: fn}
+	if r.hash == nil {
+		r.hash = crc32.ChecksumIEEE
+	}
+	return r
+}
+
+// AddNode adds a node with weight 1
+func (r *Rendezvous) AddNode(node string) error {
+	return r.AddNodeWithWeight(node, 1)
+}
+
+// AddNodeWithWeight adds a node that wins a share of keys proportional to its weight
+func (r *Rendezvous) AddNodeWithWeight(node string, weight int) error {
+	if weight < 1 {
+		return ErrInvalidWeight
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx := r.index(node)
+	if idx < len(r.nodes) && r.nodes[idx].name == node {
+		return ErrNodeExists
+	}
+
+	r.nodes = append(r.nodes, rendezvousNode{})
+	copy(r.nodes[idx+1:], r.nodes[idx:])
+	r.nodes[idx] = rendezvousNode{name: node, hash: r.hash([]byte(node)), weight: weight}
+	return nil
+}
+
+// RemoveNode removes a node, only the keys it won move to other nodes
+func (r *Rendezvous) RemoveNode(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx := r.index(node)
+	if idx < len(r.nodes) && r.nodes[idx].name == node {
+		r.nodes = append(r.nodes[:idx], r.nodes[idx+1:]...)
+	}
+}
+
+// SetWeight changes the weight of a node, keys only move to or from that node
+func (r *Rendezvous) SetWeight(node string, weight int) error {
+	if weight < 1 {
+		return ErrInvalidWeight
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx := r.index(node)
+	if idx == len(r.nodes) || r.nodes[idx].name != node {
+		return ErrNodeNotFound
+	}
+	r.nodes[idx].weight = weight
+	return nil
+}
+
+// Weight returns the weight of a node, zero when the node is not present
+func (r *Rendezvous) Weight(node string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	idx := r.index(node)
+	if idx < len(r.nodes) && r.nodes[idx].name == node {
+		return r.nodes[idx].weight
+	}
+	return 0
+}
+
+// Nodes returns the nodes sorted by name
+func (r *Rendezvous) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nodes := make([]string, len(r.nodes))
+	for i := range r.nodes {
+		nodes[i] = r.nodes[i].name
+	}
+	return nodes
+}
+
+// GetNode returns the node with the highest score for the provided key
+func (r *Rendezvous) GetNode(key string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.nodes) == 0 {
+		return ""
+	}
+
+	hash := r.hash([]byte(key))
+	best, bestScore := 0, math.Inf(-1)
+	for i := range r.nodes {
+		if score := r.nodes[i].score(hash); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return r.nodes[best].name
+}
+
+// GetNodes returns the n nodes with the highest scores for the provided key,
+// in preference order. ErrInsufficientNodes is returned when fewer than n
+// nodes are present.
+func (r *Rendezvous) GetNodes(key string, n int) ([]string, error) {
+	if n < 1 {
+		return nil, ErrInvalidCount
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.nodes) < n {
+		return nil, ErrInsufficientNodes
+	}
+
+	hash := r.hash([]byte(key))
+	scores := make([]float64, len(r.nodes))
+	order := make([]int, len(r.nodes))
+	for i := range r.nodes {
+		scores[i] = r.nodes[i].score(hash)
+		order[i] = i
+	}
+	// Nodes are sorted by name, so a stable sort breaks ties deterministically
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = r.nodes[order[i]].name
+	}
+	return nodes, nil
+}
+
+// index returns the position of the node in the sorted node list, or where
+// it would be inserted. Callers must hold the lock.
+func (r *Rendezvous) index(node string) int {
+	return sort.Search(len(r.nodes), func(i int) bool {
+		return r.nodes[i].name >= node
+	})
+}
+
+// score implements the logarithmic method of weighted rendezvous hashing:
+// -weight / ln(u), where u is the key and node hash pair mapped into (0, 1).
+func (n *rendezvousNode) score(key uint32) float64 {
+	h := fmix64(uint64(key)<<32 | uint64(n.hash))
+	u := (float64(h>>11) + 0.5) / (1 << 53)
+	return -float64(n.weight) / math.Log(u)
+}
@@ -0,0 +1,17 @@
+package ch
+
+import "fmt"
+
+func ExampleNewRendezvous() {
+	r := NewRendezvous(nil)
+	r.AddNode("NodeA")
+	r.AddNode("NodeB")
+	_ = r.AddNodeWithWeight("NodeC", 2)
+
+	fmt.Println("Owner:", r.GetNode("user123"))
+
+	replicas, err := r.GetNodes("user123", 2)
+	if err == nil {
+		fmt.Println("Replicas:", replicas)
+	}
+}
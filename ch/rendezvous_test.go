@@ -0,0 +1,167 @@
+package ch
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRendezvous_GetNode(t *testing.T) {
+	r := NewRendezvous(nil)
+	if node := r.GetNode("key"); node != "" {
+		t.Errorf("Expected empty string without nodes, got %s", node)
+	}
+
+	r.AddNode("NodeA")
+	r.AddNode("NodeB")
+	r.AddNode("NodeC")
+
+	node := r.GetNode("my-key")
+	if node == "" {
+		t.Errorf("Expected a valid node, but got an empty string")
+	}
+	if again := r.GetNode("my-key"); again != node {
+		t.Errorf("Expected consistent mapping, but got %s and %s", node, again)
+	}
+}
+
+func TestRendezvous_InsertionOrderIndependent(t *testing.T) {
+	a := NewRendezvous(nil)
+	b := NewRendezvous(nil)
+	for i := 0; i < 10; i++ {
+		a.AddNode("Node" + strconv.Itoa(i))
+		b.AddNode("Node" + strconv.Itoa(9-i))
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		if a.GetNode(key) != b.GetNode(key) {
+			t.Fatalf("Expected the same owner for %s regardless of insertion order", key)
+		}
+	}
+}
+
+func TestRendezvous_Distribution(t *testing.T) {
+	r := NewRendezvous(nil)
+	nodes := 5
+	for i := 0; i < nodes; i++ {
+		r.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	keys := 50000
+	counts := make(map[string]int)
+	for i := 0; i < keys; i++ {
+		counts[r.GetNode("key"+strconv.Itoa(i))]++
+	}
+
+	expected := float64(keys) / float64(nodes)
+	for node, c := range counts {
+		if float64(c) < expected*0.9 || float64(c) > expected*1.1 {
+			t.Errorf("Node %s owns %d keys, expected about %.0f", node, c, expected)
+		}
+	}
+}
+
+func TestRendezvous_WeightedDistribution(t *testing.T) {
+	r := NewRendezvous(nil)
+	_ = r.AddNodeWithWeight("Big", 3)
+	r.AddNode("Small")
+
+	keys := 50000
+	big := 0
+	for i := 0; i < keys; i++ {
+		if r.GetNode("key"+strconv.Itoa(i)) == "Big" {
+			big++
+		}
+	}
+
+	share := float64(big) / float64(keys)
+	if share < 0.72 || share > 0.78 {
+		t.Errorf("Expected Big to own about 75%% of keys, got %.3f", share)
+	}
+}
+
+func TestRendezvous_RemoveNodeMovesOnlyItsKeys(t *testing.T) {
+	r := NewRendezvous(nil)
+	for i := 0; i < 5; i++ {
+		r.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	keys := 10000
+	before := make([]string, keys)
+	for i := range before {
+		before[i] = r.GetNode("key" + strconv.Itoa(i))
+	}
+
+	r.RemoveNode("Node2")
+	for i := range before {
+		after := r.GetNode("key" + strconv.Itoa(i))
+		if before[i] != "Node2" && after != before[i] {
+			t.Fatalf("Key owned by %s moved to %s", before[i], after)
+		}
+		if after == "Node2" {
+			t.Fatalf("Key still mapped to the removed node")
+		}
+	}
+}
+
+func TestRendezvous_Weight(t *testing.T) {
+	r := NewRendezvous(nil)
+	if err := r.AddNodeWithWeight("NodeA", 0); err != ErrInvalidWeight {
+		t.Errorf("Expected %v, got %v", ErrInvalidWeight, err)
+	}
+	if err := r.SetWeight("NodeA", 2); err != ErrNodeNotFound {
+		t.Errorf("Expected %v, got %v", ErrNodeNotFound, err)
+	}
+
+	r.AddNode("NodeA")
+	if err := r.SetWeight("NodeA", 4); err != nil {
+		t.Fatal(err)
+	}
+	if w := r.Weight("NodeA"); w != 4 {
+		t.Errorf("Expected weight 4, got %d", w)
+	}
+	if w := r.Weight("NodeB"); w != 0 {
+		t.Errorf("Expected weight 0, got %d", w)
+	}
+}
+
+func TestRendezvous_GetNodes(t *testing.T) {
+	r := NewRendezvous(nil)
+	for i := 0; i < 5; i++ {
+		r.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	nodes, err := r.GetNodes("user123", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodes[0] != r.GetNode("user123") {
+		t.Errorf("Expected the top scoring node first, got %v", nodes)
+	}
+
+	r.RemoveNode(nodes[0])
+	next, _ := r.GetNodes("user123", 2)
+	if next[0] != nodes[1] || next[1] != nodes[2] {
+		t.Errorf("Expected %v after removing the primary, got %v", nodes[1:], next)
+	}
+
+	if _, err := r.GetNodes("user123", 5); err != ErrInsufficientNodes {
+		t.Errorf("Expected %v, got %v", ErrInsufficientNodes, err)
+	}
+	if _, err := r.GetNodes("user123", 0); err != ErrInvalidCount {
+		t.Errorf("Expected %v, got %v", ErrInvalidCount, err)
+	}
+}
+
+func BenchmarkRendezvous_GetNode(b *testing.B) {
+	r := NewRendezvous(nil)
+	for i := 0; i < 100; i++ {
+		r.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = r.GetNode("key" + strconv.Itoa(i))
+	}
+}
@@ -0,0 +1,22 @@
+package ch
+
+// Ring is the placement surface shared by the algorithms of this package, so
+// callers can switch between them without touching call sites
+type Ring interface {
+	// AddNode adds a node to the ring, ErrNodeExists when it is already present
+	AddNode(node string) error
+	// RemoveNode removes a node from the ring
+	RemoveNode(node string)
+	// GetNode returns the node owning the key, an empty string without nodes
+	GetNode(key string) string
+	// Nodes returns the nodes of the ring sorted by name
+	Nodes() []string
+}
+
+var (
+	_ Ring = (*Map[any])(nil)
+	_ Ring = (*Rendezvous)(nil)
+	_ Ring = (*Maglev)(nil)
+	_ Ring = (*Anchor)(nil)
+	_ Ring = (*MultiProbe)(nil)
+)
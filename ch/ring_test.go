@@ -0,0 +1,189 @@
+package ch
+
+import (
+	"strconv"
+	"testing"
+)
+
+// ringTolerance bounds the quality checks of the conformance suite
+type ringTolerance struct {
+	peakToMean float64 // Highest node load divided by the mean load
+	disruption float64 // Fraction of keys moving between nodes that did not change
+	ordered    bool    // Owners depend on the order nodes were added in
+}
+
+// testRing runs the conformance suite every Ring implementation must pass
+func testRing(t *testing.T, newRing func() Ring, tol ringTolerance) {
+	const nodes, keys = 10, 20000
+
+	fill := func(r Ring, order []int) Ring {
+		for _, i := range order {
+			r.AddNode("Node" + strconv.Itoa(i))
+		}
+		return r
+	}
+	ascending := make([]int, nodes)
+	descending := make([]int, nodes)
+	for i := range ascending {
+		ascending[i] = i
+		descending[i] = nodes - 1 - i
+	}
+	owners := func(r Ring) []string {
+		owners := make([]string, keys)
+		for i := range owners {
+			owners[i] = r.GetNode("key" + strconv.Itoa(i))
+		}
+		return owners
+	}
+
+	t.Run("Empty", func(t *testing.T) {
+		r := newRing()
+		if node := r.GetNode("key"); node != "" {
+			t.Errorf("Expected empty string without nodes, got %s", node)
+		}
+		if len(r.Nodes()) != 0 {
+			t.Errorf("Expected no nodes, got %v", r.Nodes())
+		}
+	})
+
+	t.Run("Nodes", func(t *testing.T) {
+		r := fill(newRing(), descending)
+		r.RemoveNode("Node5")
+		r.RemoveNode("Missing")
+
+		got := r.Nodes()
+		want := []string{"Node0", "Node1", "Node2", "Node3", "Node4", "Node6", "Node7", "Node8", "Node9"}
+		if len(got) != len(want) {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("Expected %v, got %v", want, got)
+			}
+		}
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		r := newRing()
+		if err := r.AddNode("Node0"); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.AddNode("Node0"); err != ErrNodeExists {
+			t.Errorf("Expected %v, got %v", ErrNodeExists, err)
+		}
+		if len(r.Nodes()) != 1 {
+			t.Errorf("Expected a single node, got %v", r.Nodes())
+		}
+	})
+
+	t.Run("Determinism", func(t *testing.T) {
+		a := owners(fill(newRing(), ascending))
+		again := owners(fill(newRing(), ascending))
+		reversed := owners(fill(newRing(), descending))
+		for i := range a {
+			if a[i] != again[i] || (!tol.ordered && a[i] != reversed[i]) {
+				t.Fatalf("Key %d mapped to %s, %s and %s", i, a[i], again[i], reversed[i])
+			}
+		}
+	})
+
+	t.Run("Balance", func(t *testing.T) {
+		counts := make(map[string]int)
+		for _, owner := range owners(fill(newRing(), ascending)) {
+			counts[owner]++
+		}
+		if len(counts) != nodes {
+			t.Fatalf("Expected keys on %d nodes, got %d", nodes, len(counts))
+		}
+
+		peak := 0
+		for _, c := range counts {
+			if c > peak {
+				peak = c
+			}
+		}
+		if ratio := float64(peak) * nodes / keys; ratio > tol.peakToMean {
+			t.Errorf("Peak-to-mean load %.3f above %.3f", ratio, tol.peakToMean)
+		}
+	})
+
+	t.Run("MinimalDisruption", func(t *testing.T) {
+		r := fill(newRing(), ascending)
+		before := owners(r)
+
+		r.AddNode("Extra")
+		added := owners(r)
+		r.RemoveNode("Extra")
+		r.RemoveNode("Node0")
+		removed := owners(r)
+
+		addedMoves, removedMoves := 0, 0
+		for i := range before {
+			if added[i] != before[i] && added[i] != "Extra" {
+				addedMoves++
+			}
+			if removed[i] == "Node0" {
+				t.Fatalf("Key %d still mapped to the removed node", i)
+			}
+			if before[i] != "Node0" && removed[i] != before[i] {
+				removedMoves++
+			}
+		}
+		if ratio := float64(addedMoves) / keys; ratio > tol.disruption {
+			t.Errorf("Adding a node moved %.3f of keys between other nodes", ratio)
+		}
+		if ratio := float64(removedMoves) / keys; ratio > tol.disruption {
+			t.Errorf("Removing a node moved %.3f of keys between other nodes", ratio)
+		}
+	})
+}
+
+func TestRing_Map(t *testing.T) {
+	testRing(t, func() Ring { return New[string](200, nil) }, ringTolerance{peakToMean: 1.5})
+}
+
+func TestRing_Rendezvous(t *testing.T) {
+	testRing(t, func() Ring { return NewRendezvous(nil) }, ringTolerance{peakToMean: 1.1})
+}
+
+func TestRing_Maglev(t *testing.T) {
+	testRing(t, func() Ring {
+		mg, _ := NewMaglev(DefaultMaglevTableSize, nil)
+		return mg
+	}, ringTolerance{peakToMean: 1.1, disruption: 0.05})
+}
+
+func TestRing_Anchor(t *testing.T) {
+	testRing(t, func() Ring {
+		ah, _ := NewAnchor(DefaultAnchorCapacity, nil)
+		return ah
+	}, ringTolerance{peakToMean: 1.1, ordered: true})
+}
+
+func TestRing_MultiProbe(t *testing.T) {
+	testRing(t, func() Ring {
+		mp, _ := NewMultiProbe(DefaultProbes, nil)
+		return mp
+	}, ringTolerance{peakToMean: 1.3})
+}
+
+func TestRing_Map64(t *testing.T) {
+	tests := []struct {
+		name string
+		tol  ringTolerance
+	}{
+		{HashXXHash64, ringTolerance{peakToMean: 1.3}},
+		{HashMurmur3, ringTolerance{peakToMean: 1.3}},
+		{HashFNV1a, ringTolerance{peakToMean: 1.3}},
+	}
+
+	for _, tt := range tests {
+		name := tt.name
+		t.Run(name, func(t *testing.T) {
+			testRing(t, func() Ring {
+				m, _ := NewWithHash[string](200, name)
+				return m
+			}, tt.tol)
+		})
+	}
+}
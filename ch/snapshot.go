@@ -0,0 +1,101 @@
+package ch
+
+import "sort"
+
+// ringSnapshot is an immutable copy of the ring positions and their owners.
+// Lookups read the latest one without taking the lock.
+type ringSnapshot struct {
+	hash     Hash64
+	hashBits int
+	keys     []uint64
+	owners   []string             // owners[i] owns keys[i]
+	states   map[string]NodeState // Nodes that are not active
+}
+
+// snapshot returns the latest snapshot of the ring, an empty one for a zero
+// Map. It is safe to call with or without the lock.
+func (m *Map[T]) snapshot() *ringSnapshot {
+	if s := m.ring.Load(); s != nil {
+		return s
+	}
+	return &ringSnapshot{}
+}
+
+// swap publishes a snapshot of the ring for lookups. The keys slice is shared,
+// which is safe because membership changes always build a new one.
+// Callers must hold the lock.
+func (m *Map[T]) swap() {
+	s := &ringSnapshot{
+		hash:     m.hash,
+		hashBits: m.hashBits,
+		keys:     m.keys,
+		owners:   make([]string, len(m.keys)),
+	}
+	for i, hash := range m.keys {
+		s.owners[i] = m.hashMap[hash]
+	}
+	if len(m.states) > 0 {
+		s.states = make(map[string]NodeState, len(m.states))
+		for node, state := range m.states {
+			s.states[node] = state
+		}
+	}
+	m.ring.Store(s)
+}
+
+// search returns the index of the first position clockwise from the hash
+func (s *ringSnapshot) search(hash uint64) int {
+	idx := sort.Search(len(s.keys), func(i int) bool {
+		return s.keys[i] >= hash
+	})
+	if idx == len(s.keys) {
+		idx = 0
+	}
+	return idx
+}
+
+// ownerAt returns the owner of the position at the index, wrapping to the
+// first position past the end, and an empty string without positions
+func (s *ringSnapshot) ownerAt(idx int) string {
+	if len(s.owners) == 0 {
+		return ""
+	}
+	if idx == len(s.owners) {
+		idx = 0
+	}
+	return s.owners[idx]
+}
+
+// owner returns the owner of the first position clockwise from the hash
+func (s *ringSnapshot) owner(hash uint64) string {
+	if len(s.keys) == 0 {
+		return ""
+	}
+	return s.owners[s.search(hash)]
+}
+
+// locate walks clockwise from the hash to the first owner that is not down
+func (s *ringSnapshot) locate(hash uint64) Location {
+	if len(s.keys) == 0 {
+		return Location{}
+	}
+	idx := s.search(hash)
+	loc := Location{Owner: s.owners[idx]}
+	if len(s.states) == 0 {
+		loc.Node = loc.Owner
+		return loc
+	}
+
+	for i := 0; i < len(s.keys); i++ {
+		node := s.owners[(idx+i)%len(s.keys)]
+		state := s.states[node]
+		if state != StateDown {
+			loc.Node, loc.State = node, state
+			return loc
+		}
+		if !contains(loc.Skipped, node) {
+			loc.Skipped = append(loc.Skipped, node)
+		}
+	}
+	return loc
+}
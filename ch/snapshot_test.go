@@ -0,0 +1,104 @@
+package ch
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSnapshot_MatchesRing(t *testing.T) {
+	ch := New[string](30, collidingHash)
+	_ = ch.AddNodes("NodeA", "NodeB", "NodeC")
+	_ = ch.SetWeight("NodeB", 3)
+	ch.RemoveNode("NodeA")
+	_ = ch.SetNodes(map[string]int{"NodeB": 1, "NodeC": 2, "NodeD": 1})
+
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		if got, want := ch.GetNode(key), ch.owner(key); got != want {
+			t.Fatalf("Snapshot maps %s to %s, ring to %s", key, got, want)
+		}
+	}
+}
+
+func TestSnapshot_ZeroMap(t *testing.T) {
+	var ch Map[string]
+	if node := ch.GetNode("key"); node != "" {
+		t.Errorf("Expected empty string for a zero Map, got %s", node)
+	}
+}
+
+func TestSnapshot_ConcurrentLookups(t *testing.T) {
+	ch := New[string](20, nil)
+	nodes := make([]string, 20)
+	for i := range nodes {
+		nodes[i] = "Node" + strconv.Itoa(i)
+	}
+	_ = ch.AddNodes(nodes[:10]...)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if node := ch.GetNode("key" + strconv.Itoa(i)); node == "" {
+					t.Error("Expected a node while the ring is never empty")
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 10; i < len(nodes); i++ {
+		_ = ch.AddNode(nodes[i])
+		ch.RemoveNode(nodes[i-10])
+		_ = ch.SetWeight(nodes[i], 2)
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// BenchmarkSnapshot_GetNodeParallel compares lock-free lookups with lookups
+// under the read lock as GOMAXPROCS grows
+func BenchmarkSnapshot_GetNodeParallel(b *testing.B) {
+	ch := New[string](100, nil)
+	for i := 0; i < 100; i++ {
+		_ = ch.AddNode("Node" + strconv.Itoa(i))
+	}
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+
+	lookups := map[string]func(key string) string{
+		"Snapshot": ch.GetNode,
+		"RWMutex": func(key string) string {
+			ch.mu.RLock()
+			defer ch.mu.RUnlock()
+			return ch.lookup(ch.hash([]byte(key)), StateDown)
+		},
+	}
+
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	for _, name := range []string{"Snapshot", "RWMutex"} {
+		lookup := lookups[name]
+		for procs := 1; procs <= runtime.NumCPU(); procs *= 2 {
+			b.Run(name+"/procs-"+strconv.Itoa(procs), func(b *testing.B) {
+				runtime.GOMAXPROCS(procs)
+				b.RunParallel(func(pb *testing.PB) {
+					for i := 0; pb.Next(); i++ {
+						lookup(keys[i%len(keys)])
+					}
+				})
+			})
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package ch
+
+// NodeState is the health of a node on the ring. A node keeps its virtual
+// nodes whatever its state, so the ring layout never changes with health and
+// keys return to a node exactly when it recovers.
+type NodeState int
+
+const (
+	// StateActive nodes serve their keys and take new load
+	StateActive NodeState = iota
+	// StateDraining nodes keep serving their keys but take no new load from
+	// Acquire, so their work can wind down before they leave
+	StateDraining
+	// StateDown nodes are passed over, their keys served by the next node
+	// clockwise that is not down
+	StateDown
+)
+
+func (s NodeState) String() string {
+	switch s {
+	case StateActive:
+		return "Active"
+	case StateDraining:
+		return "Draining"
+	case StateDown:
+		return "Down"
+	}
+	return "Unknown"
+}
+
+// Location describes which node serves a key and why
+type Location struct {
+	Node    string    // Node serving the key, empty when every node is down
+	Owner   string    // Node owning the key on the ring
+	State   NodeState // State of the serving node
+	Skipped []string  // Down nodes passed over clockwise, in order
+}
+
+// Fallback reports whether the key is served by another node than its owner
+func (l Location) Fallback() bool {
+	return l.Node != l.Owner
+}
+
+// SetState changes the state of a node already on the ring
+func (m *Map[T]) SetState(node string, state NodeState) error {
+	if state < StateActive || state > StateDown {
+		return ErrInvalidState
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.weights[node]; !ok {
+		return ErrNodeNotFound
+	}
+	if state == m.states[node] {
+		return nil
+	}
+
+	if state == StateActive {
+		delete(m.states, node)
+	} else {
+		if m.states == nil {
+			m.states = make(map[string]NodeState)
+		}
+		m.states[node] = state
+	}
+	m.swap()
+	return nil
+}
+
+// State returns the state of a node, StateActive for a node not on the ring
+func (m *Map[T]) State(node string) NodeState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.states[node]
+}
+
+// Locate returns the node serving the key along with its ring owner and the
+// down nodes passed over to reach it. Load bounds are not applied.
+func (m *Map[T]) Locate(key string) Location {
+	s := m.snapshot()
+	if len(s.keys) == 0 {
+		return Location{}
+	}
+	return s.locate(s.hash([]byte(key)))
+}
+
+func contains(nodes []string, node string) bool {
+	for _, n := range nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
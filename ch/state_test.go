@@ -0,0 +1,206 @@
+package ch
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestState_DownNodeFallsThrough(t *testing.T) {
+	ch := New[string](50, nil)
+	_ = ch.AddNodes("NodeA", "NodeB", "NodeC", "NodeD")
+	removed := New[string](50, nil)
+	_ = removed.AddNodes("NodeA", "NodeC", "NodeD")
+
+	keys := 2000
+	before := make([]string, keys)
+	for i := range before {
+		before[i] = ch.GetNode("key" + strconv.Itoa(i))
+	}
+	layout := ch.snapshot()
+
+	if err := ch.SetState("NodeB", StateDown); err != nil {
+		t.Fatal(err)
+	}
+	if after := ch.snapshot(); !reflect.DeepEqual(after.keys, layout.keys) || !reflect.DeepEqual(after.owners, layout.owners) {
+		t.Error("Expected a down node to keep the ring layout")
+	}
+	for i := range before {
+		key := "key" + strconv.Itoa(i)
+		// Keys of the down node go where they would go without it
+		if got, want := ch.GetNode(key), removed.GetNode(key); got != want {
+			t.Fatalf("Expected %s on %s with NodeB down, got %s", key, want, got)
+		}
+
+		loc := ch.Locate(key)
+		if loc.Owner != before[i] || loc.Node != ch.GetNode(key) || loc.Fallback() != (before[i] == "NodeB") {
+			t.Fatalf("Unexpected location of %s: %+v", key, loc)
+		}
+		if loc.Fallback() && (len(loc.Skipped) != 1 || loc.Skipped[0] != "NodeB") {
+			t.Fatalf("Expected NodeB to be skipped for %s, got %v", key, loc.Skipped)
+		}
+	}
+
+	// Keys return to the node exactly when it recovers
+	_ = ch.SetState("NodeB", StateActive)
+	for i := range before {
+		if got := ch.GetNode("key" + strconv.Itoa(i)); got != before[i] {
+			t.Fatalf("Expected key%d back on %s, got %s", i, before[i], got)
+		}
+	}
+}
+
+func TestState_SkippedInOrder(t *testing.T) {
+	ch := New[string](20, nil)
+	_ = ch.AddNodes("NodeA", "NodeB", "NodeC")
+	_ = ch.SetState("NodeA", StateDown)
+	_ = ch.SetState("NodeB", StateDown)
+	healthy := New[string](20, nil)
+	_ = healthy.AddNodes("NodeA", "NodeB", "NodeC")
+
+	for i := 0; i < 200; i++ {
+		key := "key" + strconv.Itoa(i)
+		loc := ch.Locate(key)
+		if loc.Node != "NodeC" {
+			t.Fatalf("Expected NodeC to serve %s, got %+v", key, loc)
+		}
+
+		// The down nodes met clockwise before NodeC
+		preference, _ := healthy.GetNodes(key, 3)
+		want := make([]string, 0, 2)
+		for _, node := range preference {
+			if node == "NodeC" {
+				break
+			}
+			want = append(want, node)
+		}
+		if len(loc.Skipped) != len(want) {
+			t.Fatalf("Expected %v skipped for %s, got %v", want, key, loc.Skipped)
+		}
+		for j := range want {
+			if loc.Skipped[j] != want[j] {
+				t.Fatalf("Expected %v skipped for %s, got %v", want, key, loc.Skipped)
+			}
+		}
+	}
+
+	_ = ch.SetState("NodeC", StateDown)
+	if node := ch.GetNode("key"); node != "" {
+		t.Errorf("Expected no node while every node is down, got %s", node)
+	}
+	if loc := ch.Locate("key"); loc.Node != "" || len(loc.Skipped) != 3 {
+		t.Errorf("Expected every node skipped, got %+v", loc)
+	}
+}
+
+func TestState_Draining(t *testing.T) {
+	ch := New[string](50, nil)
+	_ = ch.AddNodes("NodeA", "NodeB")
+	_ = ch.SetState("NodeA", StateDraining)
+
+	for i := 0; i < 200; i++ {
+		key := "key" + strconv.Itoa(i)
+		owner := ch.Locate(key).Owner
+		if node := ch.GetNode(key); node != owner {
+			t.Fatalf("Expected a draining node to keep serving %s, got %s", key, node)
+		}
+		if node, _ := ch.Acquire(key); node != "NodeB" {
+			t.Fatalf("Expected new load to avoid the draining node, got %s", node)
+		}
+	}
+	if loads := ch.Loads(); loads["NodeA"] != 0 {
+		t.Errorf("Expected no load on the draining node, got %v", loads)
+	}
+	if loc := ch.Locate("key"); loc.Node == "NodeA" && loc.State != StateDraining {
+		t.Errorf("Expected the draining state reported, got %+v", loc)
+	}
+
+	_ = ch.SetState("NodeB", StateDown)
+	if _, err := ch.Acquire("key"); err != ErrNoNodes {
+		t.Errorf("Expected %v, got %v", ErrNoNodes, err)
+	}
+}
+
+func TestState_BoundedAndReplicas(t *testing.T) {
+	ch := New[string](50, nil)
+	_ = ch.AddNodes("NodeA", "NodeB", "NodeC")
+	_ = ch.SetLoadFactor(1.25)
+	_ = ch.SetState("NodeB", StateDown)
+
+	for i := 0; i < 200; i++ {
+		key := "key" + strconv.Itoa(i)
+		if node := ch.GetNode(key); node == "NodeB" {
+			t.Fatalf("Expected bounded lookups to skip the down node for %s", key)
+		}
+		nodes, err := ch.GetNodes(key, 2)
+		if err != nil || contains(nodes, "NodeB") {
+			t.Fatalf("Expected a preference list without NodeB, got %v, %v", nodes, err)
+		}
+	}
+	if _, err := ch.GetNodes("key", 3); err != ErrInsufficientNodes {
+		t.Errorf("Expected %v, got %v", ErrInsufficientNodes, err)
+	}
+	if _, err := ch.GetReplicas("key", 3, DomainZone, FallbackRingOrder); err != ErrInsufficientNodes {
+		t.Errorf("Expected %v, got %v", ErrInsufficientNodes, err)
+	}
+}
+
+func TestState_ResetByRestore(t *testing.T) {
+	ch := New[string](20, nil)
+	_ = ch.AddNodes("a", "b")
+	_ = ch.SetState("a", StateDown)
+
+	data, _ := ch.MarshalJSON()
+	restored := New[string](20, nil)
+	_ = restored.AddNode("b")
+	cfg, _ := restored.MarshalJSON()
+
+	// A restore dropping the down node forgets its state
+	if err := ch.UnmarshalJSON(cfg); err != nil {
+		t.Fatal(err)
+	}
+	_ = ch.AddNode("a")
+	if state := ch.State("a"); state != StateActive {
+		t.Fatalf("Expected a re-added node to be active, got %v", state)
+	}
+	owned := 0
+	for i := 0; i < 1000; i++ {
+		if ch.GetNode("key"+strconv.Itoa(i)) == "a" {
+			owned++
+		}
+	}
+	if owned == 0 {
+		t.Error("Expected the re-added node to own keys")
+	}
+
+	// States are not part of the configuration, so a restore resets them all
+	_ = ch.SetState("b", StateDraining)
+	if err := ch.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if ch.State("a") != StateActive || ch.State("b") != StateActive {
+		t.Errorf("Expected every node active after a restore, got %v and %v", ch.State("a"), ch.State("b"))
+	}
+}
+
+func TestState_Errors(t *testing.T) {
+	ch := New[string](10, nil)
+	_ = ch.AddNode("NodeA")
+
+	if err := ch.SetState("Missing", StateDown); err != ErrNodeNotFound {
+		t.Errorf("Expected %v, got %v", ErrNodeNotFound, err)
+	}
+	if err := ch.SetState("NodeA", NodeState(7)); err != ErrInvalidState {
+		t.Errorf("Expected %v, got %v", ErrInvalidState, err)
+	}
+
+	_ = ch.SetState("NodeA", StateDown)
+	ch.RemoveNode("NodeA")
+	_ = ch.AddNode("NodeA")
+	if state := ch.State("NodeA"); state != StateActive {
+		t.Errorf("Expected removing a node to reset its state, got %v", state)
+	}
+	if s := NodeState(7).String(); s != "Unknown" {
+		t.Errorf("Expected Unknown, got %s", s)
+	}
+}
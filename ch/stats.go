@@ -0,0 +1,102 @@
+package ch
+
+import (
+	"math"
+	"strconv"
+)
+
+// Stats reports how the keyspace is spread across the nodes of a ring
+type Stats struct {
+	// Ownership is the fraction of the keyspace owned by each node
+	Ownership map[string]float64
+	// StdDev is the standard deviation of the node loads, where a load is the
+	// owned fraction divided by the share the node weight entitles it to
+	StdDev float64
+	// PeakToMean is the highest node load divided by the mean load, 1 when
+	// every node owns exactly its share
+	PeakToMean float64
+}
+
+// Stats returns the exact fraction of the hash space owned by every node
+func (m *Map[T]) Stats() Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ownership := make(map[string]float64, len(m.weights))
+	for node := range m.weights {
+		ownership[node] = 0
+	}
+
+	switch len(m.keys) {
+	case 0:
+		return m.stats(ownership)
+	case 1:
+		ownership[m.hashMap[m.keys[0]]] = 1
+		return m.stats(ownership)
+	}
+
+	space := math.Ldexp(1, m.hashBits)
+	mask := uint64(math.MaxUint64) >> (64 - m.hashBits)
+	prev := m.keys[len(m.keys)-1]
+	for _, hash := range m.keys {
+		// Every position owns the arc back to the previous one, wrapping past zero
+		arc := (hash - prev) & mask
+		ownership[m.hashMap[hash]] += float64(arc) / space
+		prev = hash
+	}
+	return m.stats(ownership)
+}
+
+// Simulate hashes the provided number of synthetic keys and returns the
+// fraction of them owned by every node, as seen by GetNode without load bounds
+func (m *Map[T]) Simulate(keys int) Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ownership := make(map[string]float64, len(m.weights))
+	for node := range m.weights {
+		ownership[node] = 0
+	}
+	if len(m.keys) == 0 || keys < 1 {
+		return m.stats(ownership)
+	}
+
+	for i := 0; i < keys; i++ {
+		ownership[m.owner("key-"+strconv.Itoa(i))]++
+	}
+	for node := range ownership {
+		ownership[node] /= float64(keys)
+	}
+	return m.stats(ownership)
+}
+
+// stats derives the load statistics of an ownership report.
+// Callers must hold the lock.
+func (m *Map[T]) stats(ownership map[string]float64) Stats {
+	s := Stats{Ownership: ownership}
+	if len(ownership) == 0 || m.totalWeight == 0 {
+		return s
+	}
+
+	loads := make([]float64, 0, len(ownership))
+	mean := 0.0
+	for node, fraction := range ownership {
+		share := float64(m.weights[node]) / float64(m.totalWeight)
+		loads = append(loads, fraction/share)
+		mean += fraction / share
+	}
+	mean /= float64(len(loads))
+
+	peak, variance := 0.0, 0.0
+	for _, load := range loads {
+		if load > peak {
+			peak = load
+		}
+		variance += (load - mean) * (load - mean)
+	}
+	s.StdDev = math.Sqrt(variance / float64(len(loads)))
+	if mean > 0 {
+		s.PeakToMean = peak / mean
+	}
+	return s
+}
@@ -0,0 +1,104 @@
+package ch
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestStats_Empty(t *testing.T) {
+	s := New[string](10, nil).Stats()
+	if len(s.Ownership) != 0 || s.StdDev != 0 || s.PeakToMean != 0 {
+		t.Errorf("Expected empty stats, got %+v", s)
+	}
+}
+
+func TestStats_SinglePosition(t *testing.T) {
+	ch := New[string](1, nil)
+	_ = ch.AddNode("NodeA")
+
+	s := ch.Stats()
+	if s.Ownership["NodeA"] != 1 || s.PeakToMean != 1 || s.StdDev != 0 {
+		t.Errorf("Expected NodeA to own the whole ring, got %+v", s)
+	}
+}
+
+func TestStats_OwnershipSumsToOne(t *testing.T) {
+	for _, ch := range []*Map[string]{New[string](50, nil), New64[string](50, nil)} {
+		for i := 0; i < 8; i++ {
+			_ = ch.AddNode("Node" + strconv.Itoa(i))
+		}
+
+		sum := 0.0
+		for _, fraction := range ch.Stats().Ownership {
+			sum += fraction
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("Expected fractions of a %d-bit ring to sum to 1, got %f", ch.hashBits, sum)
+		}
+	}
+}
+
+func TestStats_MoreReplicasImproveBalance(t *testing.T) {
+	ratio := func(replicas int) float64 {
+		ch := New64[string](replicas, nil)
+		for i := 0; i < 20; i++ {
+			_ = ch.AddNode("Node" + strconv.Itoa(i))
+		}
+		return ch.Stats().PeakToMean
+	}
+
+	few, many := ratio(5), ratio(500)
+	if many >= few {
+		t.Errorf("Expected 500 replicas (%.3f) to balance better than 5 (%.3f)", many, few)
+	}
+	if many > 1.15 {
+		t.Errorf("Expected a peak-to-mean ratio close to 1 with 500 replicas, got %.3f", many)
+	}
+}
+
+func TestStats_WeightedLoads(t *testing.T) {
+	ch := New64[string](200, nil)
+	_ = ch.AddNodeWithWeight("Big", 4)
+	_ = ch.AddNode("Small")
+
+	s := ch.Stats()
+	if s.Ownership["Big"] < 0.75 || s.Ownership["Big"] > 0.85 {
+		t.Errorf("Expected Big to own about 80%%, got %.3f", s.Ownership["Big"])
+	}
+	if s.PeakToMean > 1.2 {
+		t.Errorf("Expected loads relative to weight to be balanced, got %.3f", s.PeakToMean)
+	}
+}
+
+func TestStats_SimulateMatchesKeyspace(t *testing.T) {
+	ch := New64[string](100, nil)
+	for i := 0; i < 5; i++ {
+		_ = ch.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	exact := ch.Stats()
+	sampled := ch.Simulate(100000)
+	for node, fraction := range exact.Ownership {
+		if math.Abs(sampled.Ownership[node]-fraction) > 0.01 {
+			t.Errorf("Node %s owns %.4f of the keyspace but %.4f of sampled keys", node, fraction, sampled.Ownership[node])
+		}
+	}
+
+	if s := New[string](10, nil).Simulate(100); len(s.Ownership) != 0 {
+		t.Errorf("Expected no ownership without nodes, got %v", s.Ownership)
+	}
+}
+
+func BenchmarkStats(b *testing.B) {
+	ch := New[string](100, nil)
+	for i := 0; i < 1000; i++ {
+		_ = ch.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ch.Stats()
+	}
+}
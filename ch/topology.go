@@ -0,0 +1,141 @@
+package ch
+
+// Topology locates a node in the failure domain hierarchy. Labels are scoped
+// by their parent, so zone "a" of two regions are two distinct zones.
+type Topology struct {
+	Region string
+	Zone   string
+	Rack   string
+}
+
+// Domain is a level of the failure domain hierarchy
+type Domain int
+
+const (
+	DomainRegion Domain = iota + 1
+	DomainZone
+	DomainRack
+)
+
+// Fallback decides how GetReplicas completes a replica set when the ring has
+// fewer distinct failure domains than replicas
+type Fallback int
+
+const (
+	// FallbackStrict fails with ErrInsufficientDomains
+	FallbackStrict Fallback = iota
+	// FallbackBalanced reuses failure domains as evenly as possible, each
+	// domain getting a second replica before any gets a third
+	FallbackBalanced
+	// FallbackRingOrder fills the missing replicas with the next unused nodes
+	// clockwise, whatever their failure domain
+	FallbackRingOrder
+)
+
+// SetTopology labels a node already on the ring with its failure domains.
+// Nodes without labels share a single unnamed domain at every level.
+func (m *Map[T]) SetTopology(node string, topology Topology) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.weights[node]; !ok {
+		return ErrNodeNotFound
+	}
+	if m.topology == nil {
+		m.topology = make(map[string]Topology)
+	}
+	m.topology[node] = topology
+	return nil
+}
+
+// Topology returns the failure domain labels of a node
+func (m *Map[T]) Topology(node string) Topology {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.topology[node]
+}
+
+// GetReplicas returns n nodes for the key, each in a distinct failure domain
+// at the given level when the ring has enough of them, and completed by the
+// fallback policy otherwise. The replicas are taken walking clockwise from
+// the key and returned in ring order, so the first one is the owner of the
+// key and placement moves as little as the ring does.
+func (m *Map[T]) GetReplicas(key string, n int, domain Domain, fallback Fallback) ([]string, error) {
+	if n < 1 {
+		return nil, ErrInvalidCount
+	}
+	if domain < DomainRegion || domain > DomainRack {
+		return nil, ErrInvalidDomain
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.weights) < n || len(m.keys) == 0 {
+		return nil, ErrInsufficientNodes
+	}
+
+	// Every node that is not down, in the order its first virtual node is met
+	// clockwise
+	order := make([]string, 0, len(m.weights))
+	seen := make(map[string]struct{}, len(m.weights))
+	idx := m.search(m.hash([]byte(key)))
+	for i := 0; i < len(m.keys) && len(seen) < len(m.weights); i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if _, ok := seen[node]; !ok {
+			seen[node] = struct{}{}
+			if m.states[node] != StateDown {
+				order = append(order, node)
+			}
+		}
+	}
+
+	// Each pass allows one more replica per domain than the previous one
+	picked := make([]bool, len(order))
+	used := make(map[Topology]int)
+	count := 0
+	for limit := 1; count < n; limit++ {
+		if limit > 1 && fallback == FallbackStrict {
+			return nil, ErrInsufficientDomains
+		}
+
+		found := false
+		for i, node := range order {
+			if count == n {
+				break
+			}
+			d := m.domain(node, domain)
+			if picked[i] || (used[d] >= limit && !(limit > 1 && fallback == FallbackRingOrder)) {
+				continue
+			}
+			picked[i] = true
+			used[d]++
+			count++
+			found = true
+		}
+		if !found {
+			return nil, ErrInsufficientNodes
+		}
+	}
+
+	replicas := make([]string, 0, n)
+	for i, node := range order {
+		if picked[i] {
+			replicas = append(replicas, node)
+		}
+	}
+	return replicas, nil
+}
+
+// domain returns the labels of a node down to the level, which identify its
+// failure domain at that level. Callers must hold the lock.
+func (m *Map[T]) domain(node string, level Domain) Topology {
+	t := m.topology[node]
+	switch level {
+	case DomainRegion:
+		return Topology{Region: t.Region}
+	case DomainZone:
+		return Topology{Region: t.Region, Zone: t.Zone}
+	}
+	return t
+}
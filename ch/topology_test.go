@@ -0,0 +1,172 @@
+package ch
+
+import (
+	"strconv"
+	"testing"
+)
+
+// newTopologyMap creates a ring of three nodes in each of the zones
+func newTopologyMap(t *testing.T, zones ...string) *Map[string] {
+	t.Helper()
+	ch := New[string](50, nil)
+	for _, zone := range zones {
+		for i := 0; i < 3; i++ {
+			node := zone + "-node" + strconv.Itoa(i)
+			_ = ch.AddNode(node)
+			if err := ch.SetTopology(node, Topology{Region: "eu", Zone: zone, Rack: strconv.Itoa(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return ch
+}
+
+// isSubsequence reports whether every node of sub appears in nodes, in order
+func isSubsequence(sub, nodes []string) bool {
+	i := 0
+	for _, node := range nodes {
+		if i < len(sub) && sub[i] == node {
+			i++
+		}
+	}
+	return i == len(sub)
+}
+
+func TestTopology_DistinctZones(t *testing.T) {
+	ch := newTopologyMap(t, "a", "b", "c")
+
+	for i := 0; i < 200; i++ {
+		key := "key" + strconv.Itoa(i)
+		replicas, err := ch.GetReplicas(key, 3, DomainZone, FallbackStrict)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zones := make(map[string]struct{})
+		for _, node := range replicas {
+			zones[ch.Topology(node).Zone] = struct{}{}
+		}
+		if len(zones) != 3 {
+			t.Fatalf("Expected replicas of %s in 3 zones, got %v", key, replicas)
+		}
+		if replicas[0] != ch.GetNode(key) {
+			t.Fatalf("Expected the owner %s first, got %v", ch.GetNode(key), replicas)
+		}
+		all, _ := ch.GetNodes(key, 9)
+		if !isSubsequence(replicas, all) {
+			t.Fatalf("Expected replicas %v in ring order %v", replicas, all)
+		}
+	}
+}
+
+func TestTopology_Fallback(t *testing.T) {
+	ch := newTopologyMap(t, "a", "b")
+	key := "key"
+
+	if _, err := ch.GetReplicas(key, 3, DomainZone, FallbackStrict); err != ErrInsufficientDomains {
+		t.Errorf("Expected %v, got %v", ErrInsufficientDomains, err)
+	}
+
+	balanced, err := ch.GetReplicas(key, 4, DomainZone, FallbackBalanced)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zones := make(map[string]int)
+	for _, node := range balanced {
+		zones[ch.Topology(node).Zone]++
+	}
+	if zones["a"] != 2 || zones["b"] != 2 {
+		t.Errorf("Expected two replicas per zone, got %v", zones)
+	}
+
+	filled, err := ch.GetReplicas(key, 4, DomainZone, FallbackRingOrder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, _ := ch.GetNodes(key, 6)
+	spread, _ := ch.GetReplicas(key, 2, DomainZone, FallbackStrict)
+	for _, node := range all {
+		if len(spread) == 4 {
+			break
+		}
+		if node != spread[0] && node != spread[1] {
+			spread = append(spread, node)
+		}
+	}
+	for _, node := range spread {
+		found := false
+		for _, f := range filled {
+			found = found || f == node
+		}
+		if !found {
+			t.Fatalf("Expected the first nodes clockwise %v, got %v", spread, filled)
+		}
+	}
+	if !isSubsequence(filled, all) {
+		t.Errorf("Expected replicas %v in ring order %v", filled, all)
+	}
+
+	if _, err := ch.GetReplicas(key, 7, DomainZone, FallbackBalanced); err != ErrInsufficientNodes {
+		t.Errorf("Expected %v, got %v", ErrInsufficientNodes, err)
+	}
+}
+
+func TestTopology_ScopedLabels(t *testing.T) {
+	ch := New[string](50, nil)
+	_ = ch.AddNodes("NodeA", "NodeB", "NodeC")
+	_ = ch.SetTopology("NodeA", Topology{Region: "eu", Zone: "a"})
+	_ = ch.SetTopology("NodeB", Topology{Region: "us", Zone: "a"})
+
+	// The same zone name in two regions is two zones, unlabeled NodeC a third
+	if _, err := ch.GetReplicas("key", 3, DomainZone, FallbackStrict); err != nil {
+		t.Errorf("Expected 3 distinct zones, got %v", err)
+	}
+	if _, err := ch.GetReplicas("key", 3, DomainRegion, FallbackStrict); err != nil {
+		t.Errorf("Expected 3 distinct regions, got %v", err)
+	}
+	_ = ch.SetTopology("NodeC", Topology{Region: "eu", Zone: "b"})
+	if _, err := ch.GetReplicas("key", 3, DomainRegion, FallbackStrict); err != ErrInsufficientDomains {
+		t.Errorf("Expected %v, got %v", ErrInsufficientDomains, err)
+	}
+}
+
+func TestTopology_Errors(t *testing.T) {
+	ch := newTopologyMap(t, "a")
+
+	if err := ch.SetTopology("Missing", Topology{Zone: "a"}); err != ErrNodeNotFound {
+		t.Errorf("Expected %v, got %v", ErrNodeNotFound, err)
+	}
+	if _, err := ch.GetReplicas("key", 0, DomainZone, FallbackStrict); err != ErrInvalidCount {
+		t.Errorf("Expected %v, got %v", ErrInvalidCount, err)
+	}
+	if _, err := ch.GetReplicas("key", 1, Domain(0), FallbackStrict); err != ErrInvalidDomain {
+		t.Errorf("Expected %v, got %v", ErrInvalidDomain, err)
+	}
+
+	ch.RemoveNode("a-node0")
+	_ = ch.AddNode("a-node0")
+	if topology := ch.Topology("a-node0"); topology != (Topology{}) {
+		t.Errorf("Expected removing a node to drop its labels, got %v", topology)
+	}
+}
+
+func TestTopology_ResetByRestore(t *testing.T) {
+	ch := newTopologyMap(t, "a")
+
+	// A restore without the labelled node forgets its labels
+	cfg := New[string](50, nil)
+	_ = cfg.AddNodes("a-node1", "a-node2")
+	data, _ := cfg.MarshalJSON()
+	if err := ch.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = ch.AddNode("a-node0")
+	if topology := ch.Topology("a-node0"); topology != (Topology{}) {
+		t.Errorf("Expected a re-added node to have no labels, got %v", topology)
+	}
+	// Labels are not encoded, so the kept nodes lose theirs too
+	if topology := ch.Topology("a-node1"); topology != (Topology{}) {
+		t.Errorf("Expected a restore to reset every label, got %v", topology)
+	}
+}
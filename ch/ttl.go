@@ -0,0 +1,162 @@
+package ch
+
+import "time"
+
+// AddKeyWithTTL stores a key-value pair that expires after the ttl, like
+// AddKey when the ttl is not positive. An expired key is removed the next
+// time GetKey reads it or the janitor sweeps, and counts as stored until
+// then for KeysForNode, KeyCount and the eviction hook.
+func (m *Map[T]) AddKeyWithTTL(key string, value T, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.addKey(key, value) {
+		return
+	}
+	if ttl > 0 {
+		if m.expiry == nil {
+			m.expiry = make(map[string]time.Time)
+		}
+		m.expiry[key] = m.now().Add(ttl)
+	} else {
+		delete(m.expiry, key)
+	}
+}
+
+// Expiration returns when a stored key expires, the zero time when it never
+// does, and false when the key is not stored or already expired
+func (m *Map[T]) Expiration(key string) (time.Time, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.data[key]; !ok || m.expired(key) {
+		return time.Time{}, false
+	}
+	return m.expiry[key], true
+}
+
+// SetClock replaces the clock deciding when keys expire, time.Now when nil.
+// Tests can advance a fake clock to expire keys deterministically.
+func (m *Map[T]) SetClock(now func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock = now
+}
+
+// SetExpirationHook registers a function called with every expired key and
+// its value once it is removed, nil to unregister. The hook runs without the
+// lock held, but must not call StartJanitor or StopJanitor: when the janitor
+// runs the hook, they would wait for the janitor to return and deadlock.
+func (m *Map[T]) SetExpirationHook(fn func(key string, value T)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expirationHook = fn
+}
+
+// DeleteExpired removes every expired key and returns how many were removed
+func (m *Map[T]) DeleteExpired() int {
+	m.mu.Lock()
+	var keys []string
+	var values []T
+	for key := range m.expiry {
+		if m.expired(key) {
+			keys = append(keys, key)
+			values = append(values, m.data[key])
+			m.deleteKey(key)
+		}
+	}
+	hook := m.expirationHook
+	m.mu.Unlock()
+
+	if hook != nil {
+		for i, key := range keys {
+			hook(key, values[i])
+		}
+	}
+	return len(keys)
+}
+
+// StartJanitor removes the expired keys every interval in the background,
+// until StopJanitor is called. A running janitor is replaced, after waiting
+// for it to return. The interval is measured in real time, whatever the
+// clock set by SetClock.
+func (m *Map[T]) StartJanitor(interval time.Duration) error {
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+
+	// The new janitor takes the place of the old one in a single step, so
+	// concurrent starts each stop the janitor they replaced
+	stop, done := make(chan struct{}), make(chan struct{})
+	m.mu.Lock()
+	oldStop, oldDone := m.janitorStop, m.janitorDone
+	m.janitorStop, m.janitorDone = stop, done
+	m.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.DeleteExpired()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	if oldStop != nil {
+		close(oldStop)
+		<-oldDone
+	}
+	return nil
+}
+
+// StopJanitor stops the background janitor and waits for it to return. It
+// does nothing when no janitor is running.
+func (m *Map[T]) StopJanitor() {
+	m.mu.Lock()
+	stop, done := m.janitorStop, m.janitorDone
+	m.janitorStop, m.janitorDone = nil, nil
+	m.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// expireKey removes a key GetKey found expired, unless it was stored again
+// meanwhile, and calls the expiration hook
+func (m *Map[T]) expireKey(key string) {
+	m.mu.Lock()
+	value, ok := m.data[key]
+	if !ok || !m.expired(key) {
+		m.mu.Unlock()
+		return
+	}
+	m.deleteKey(key)
+	hook := m.expirationHook
+	m.mu.Unlock()
+
+	if hook != nil {
+		hook(key, value)
+	}
+}
+
+// expired reports whether a stored key has expired. Callers must hold the
+// lock.
+func (m *Map[T]) expired(key string) bool {
+	expiry, ok := m.expiry[key]
+	return ok && !m.now().Before(expiry)
+}
+
+// now returns the time of the clock. Callers must hold the lock.
+func (m *Map[T]) now() time.Time {
+	if m.clock != nil {
+		return m.clock()
+	}
+	return time.Now()
+}
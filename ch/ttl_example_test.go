@@ -0,0 +1,26 @@
+package ch
+
+import (
+	"fmt"
+	"time"
+)
+
+func ExampleMap_AddKeyWithTTL() {
+	ring := New[string](100, nil)
+	ring.AddNode("NodeA")
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ring.SetClock(func() time.Time { return now })
+	ring.SetExpirationHook(func(key, value string) {
+		fmt.Println("Expired:", key)
+	})
+
+	ring.AddKeyWithTTL("session", "alice", time.Minute)
+	now = now.Add(time.Minute)
+
+	_, ok := ring.GetKey("session")
+	fmt.Println("Found:", ok)
+	// Output:
+	// Expired: session
+	// Found: false
+}
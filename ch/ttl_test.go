@@ -0,0 +1,274 @@
+package ch
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock advanced by hand
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTTLMap(clock *fakeClock) *Map[string] {
+	ch := New[string](20, nil)
+	ch.AddNode("NodeA")
+	ch.AddNode("NodeB")
+	ch.SetClock(clock.Now)
+	return ch
+}
+
+func TestTTL_LazyExpiry(t *testing.T) {
+	clock := newFakeClock()
+	ch := newTTLMap(clock)
+
+	var expired []string
+	ch.SetExpirationHook(func(key, value string) {
+		expired = append(expired, key+"="+value)
+	})
+
+	ch.AddKeyWithTTL("session", "alice", time.Minute)
+	ch.AddKey("config", "v1")
+
+	clock.Advance(59 * time.Second)
+	if value, ok := ch.GetKey("session"); !ok || value != "alice" {
+		t.Fatalf("Expected alice before the TTL, got %q, %v", value, ok)
+	}
+
+	clock.Advance(time.Second)
+	if _, ok := ch.GetKey("session"); ok {
+		t.Error("Expected the key to expire once the TTL elapsed")
+	}
+	if _, ok := ch.GetKey("config"); !ok {
+		t.Error("Expected a key without TTL to never expire")
+	}
+
+	if len(expired) != 1 || expired[0] != "session=alice" {
+		t.Errorf("Expected a single expiration of session, got %v", expired)
+	}
+	counts := ch.KeyCounts()
+	if counts["NodeA"]+counts["NodeB"] != 1 {
+		t.Errorf("Expected the expired key to leave its partition, got %v", counts)
+	}
+}
+
+func TestTTL_Overwrite(t *testing.T) {
+	clock := newFakeClock()
+	ch := newTTLMap(clock)
+
+	// Storing again restarts the TTL
+	ch.AddKeyWithTTL("key", "v1", time.Minute)
+	clock.Advance(45 * time.Second)
+	ch.AddKeyWithTTL("key", "v2", time.Minute)
+	clock.Advance(45 * time.Second)
+	if value, ok := ch.GetKey("key"); !ok || value != "v2" {
+		t.Fatalf("Expected v2 with a restarted TTL, got %q, %v", value, ok)
+	}
+
+	// AddKey and a non-positive TTL store the key without expiration
+	ch.AddKey("key", "v3")
+	ch.AddKeyWithTTL("other", "v1", 0)
+	clock.Advance(time.Hour)
+	if _, ok := ch.GetKey("key"); !ok {
+		t.Error("Expected AddKey to clear the TTL")
+	}
+	if _, ok := ch.GetKey("other"); !ok {
+		t.Error("Expected a zero TTL to never expire")
+	}
+
+	// A non-positive TTL clears the TTL of a key stored with one
+	ch.AddKeyWithTTL("renewed", "v1", time.Minute)
+	ch.AddKeyWithTTL("renewed", "v2", 0)
+	clock.Advance(time.Hour)
+	if value, ok := ch.GetKey("renewed"); !ok || value != "v2" {
+		t.Errorf("Expected a zero TTL to clear the previous one, got %q, %v", value, ok)
+	}
+	if at, ok := ch.Expiration("renewed"); !ok || !at.IsZero() {
+		t.Errorf("Expected no expiration after a zero TTL, got %v, %v", at, ok)
+	}
+
+	// A removed key does not expire later under a new value
+	ch.AddKeyWithTTL("removed", "v1", time.Minute)
+	ch.RemoveKey("removed")
+	ch.AddKey("removed", "v2")
+	clock.Advance(time.Hour)
+	if _, ok := ch.GetKey("removed"); !ok {
+		t.Error("Expected RemoveKey to clear the TTL")
+	}
+}
+
+func TestTTL_Expiration(t *testing.T) {
+	clock := newFakeClock()
+	ch := newTTLMap(clock)
+
+	ch.AddKeyWithTTL("session", "alice", time.Minute)
+	ch.AddKey("config", "v1")
+
+	if at, ok := ch.Expiration("session"); !ok || !at.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("Expected an expiration in a minute, got %v, %v", at, ok)
+	}
+	if at, ok := ch.Expiration("config"); !ok || !at.IsZero() {
+		t.Errorf("Expected the zero time without TTL, got %v, %v", at, ok)
+	}
+	if _, ok := ch.Expiration("missing"); ok {
+		t.Error("Expected no expiration for a missing key")
+	}
+
+	clock.Advance(time.Minute)
+	if _, ok := ch.Expiration("session"); ok {
+		t.Error("Expected no expiration for an expired key")
+	}
+}
+
+func TestTTL_DeleteExpired(t *testing.T) {
+	clock := newFakeClock()
+	ch := newTTLMap(clock)
+
+	expired := make(map[string]string)
+	ch.SetExpirationHook(func(key, value string) {
+		expired[key] = value
+	})
+
+	for i := 0; i < 10; i++ {
+		ch.AddKeyWithTTL("short"+strconv.Itoa(i), "value", time.Minute)
+		ch.AddKeyWithTTL("long"+strconv.Itoa(i), "value", time.Hour)
+	}
+
+	clock.Advance(time.Minute)
+	if n := ch.DeleteExpired(); n != 10 {
+		t.Errorf("Expected 10 expired keys, got %d", n)
+	}
+	if len(expired) != 10 {
+		t.Errorf("Expected the hook to run for 10 keys, got %d", len(expired))
+	}
+	if n := ch.DeleteExpired(); n != 0 {
+		t.Errorf("Expected nothing left to expire, got %d", n)
+	}
+
+	counts := ch.KeyCounts()
+	if counts["NodeA"]+counts["NodeB"] != 10 {
+		t.Errorf("Expected 10 keys left, got %v", counts)
+	}
+}
+
+func TestTTL_Janitor(t *testing.T) {
+	clock := newFakeClock()
+	ch := newTTLMap(clock)
+
+	if err := ch.StartJanitor(0); err != ErrInvalidInterval {
+		t.Errorf("Expected %v, got %v", ErrInvalidInterval, err)
+	}
+
+	expired := make(chan string, 1)
+	ch.SetExpirationHook(func(key, value string) {
+		expired <- key
+	})
+	ch.AddKeyWithTTL("session", "alice", time.Minute)
+
+	if err := ch.StartJanitor(time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	// Restarting replaces the running janitor
+	if err := ch.StartJanitor(time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Minute)
+	select {
+	case key := <-expired:
+		if key != "session" {
+			t.Errorf("Expected session to expire, got %s", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the janitor to remove the expired key")
+	}
+
+	ch.StopJanitor()
+	ch.StopJanitor()
+
+	// A stopped janitor leaves expired keys to GetKey
+	ch.AddKeyWithTTL("other", "bob", time.Minute)
+	clock.Advance(time.Minute)
+	time.Sleep(20 * time.Millisecond)
+	if counts := ch.KeyCounts(); counts["NodeA"]+counts["NodeB"] != 1 {
+		t.Errorf("Expected the expired key to stay until read, got %v", counts)
+	}
+}
+
+func TestTTL_ConcurrentJanitors(t *testing.T) {
+	ch := newTTLMap(newFakeClock())
+	before := runtime.NumGoroutine()
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for j := 0; j < 20; j++ {
+				_ = ch.StartJanitor(time.Millisecond)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	ch.StopJanitor()
+
+	// Every janitor was stopped by the start that replaced it or by StopJanitor
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d goroutines, got %d", before, runtime.NumGoroutine())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTTL_Concurrent(t *testing.T) {
+	clock := newFakeClock()
+	ch := newTTLMap(clock)
+	_ = ch.StartJanitor(time.Millisecond)
+	defer ch.StopJanitor()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := "key" + strconv.Itoa(i%50)
+				ch.AddKeyWithTTL(key, strconv.Itoa(w), time.Second)
+				ch.GetKey(key)
+				clock.Advance(100 * time.Millisecond)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	clock.Advance(time.Second)
+	ch.DeleteExpired()
+	if counts := ch.KeyCounts(); counts["NodeA"]+counts["NodeB"] != 0 {
+		t.Errorf("Expected every key to expire, got %v", counts)
+	}
+}
@@ -0,0 +1,67 @@
+package ch
+
+// AddNodeWithWeight adds a node that owns weight times as many virtual nodes
+// as a node added with AddNode, and so a proportional share of the ring.
+// Use SetWeight to change the weight of a node that is already present.
+func (m *Map[T]) AddNodeWithWeight(node string, weight int) error {
+	if weight < 1 {
+		return ErrInvalidWeight
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.weights[node]; ok {
+		return ErrNodeExists
+	}
+
+	before := m.watch()
+	events, _ := m.change(nil, []nodeConfig{{Name: node, Weight: weight}})
+	m.publish(before, events)
+	return nil
+}
+
+// SetWeight changes the weight of a node already on the ring. Virtual node
+// positions do not depend on the weight, so only the virtual nodes above the
+// smaller of the two weights are added or removed and the rest of the
+// keyspace keeps its owner.
+func (m *Map[T]) SetWeight(node string, weight int) error {
+	if weight < 1 {
+		return ErrInvalidWeight
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.weights[node]
+	if !ok {
+		return ErrNodeNotFound
+	}
+	if weight == old {
+		return nil
+	}
+
+	before := m.watch()
+	events, _ := m.change(nil, []nodeConfig{{Name: node, Weight: weight}})
+	m.publish(before, events)
+	return nil
+}
+
+// Weight returns the weight of a node, zero when the node is not on the ring
+func (m *Map[T]) Weight(node string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.weights[node]
+}
+
+// Weights returns the weight of every node on the ring
+func (m *Map[T]) Weights() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	weights := make(map[string]int, len(m.weights))
+	for node, weight := range m.weights {
+		weights[node] = weight
+	}
+	return weights
+}
@@ -0,0 +1,121 @@
+package ch
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestWeight_InvalidWeight(t *testing.T) {
+	ch := New[string](10, nil)
+	if err := ch.AddNodeWithWeight("NodeA", 0); err != ErrInvalidWeight {
+		t.Errorf("Expected %v, got %v", ErrInvalidWeight, err)
+	}
+
+	ch.AddNode("NodeA")
+	if err := ch.SetWeight("NodeA", -1); err != ErrInvalidWeight {
+		t.Errorf("Expected %v, got %v", ErrInvalidWeight, err)
+	}
+	if err := ch.SetWeight("NodeB", 2); err != ErrNodeNotFound {
+		t.Errorf("Expected %v, got %v", ErrNodeNotFound, err)
+	}
+}
+
+func TestWeight_Readable(t *testing.T) {
+	ch := New[string](10, nil)
+	ch.AddNode("NodeA")
+	_ = ch.AddNodeWithWeight("NodeB", 16)
+
+	weights := ch.Weights()
+	if weights["NodeA"] != 1 || weights["NodeB"] != 16 {
+		t.Errorf("Unexpected weights %v", weights)
+	}
+	if ch.Weight("NodeC") != 0 {
+		t.Errorf("Expected zero weight for a missing node")
+	}
+	if len(ch.keys) != 170 {
+		t.Errorf("Expected 170 virtual nodes, got %d", len(ch.keys))
+	}
+}
+
+func TestWeight_ProportionalOwnership(t *testing.T) {
+	ch := New[string](200, nil)
+	_ = ch.AddNodeWithWeight("Big", 4)
+	for i := 0; i < 4; i++ {
+		ch.AddNode("Small" + strconv.Itoa(i))
+	}
+
+	counts := make(map[string]int)
+	keys := 50000
+	for i := 0; i < keys; i++ {
+		counts[ch.GetNode("key"+strconv.Itoa(i))]++
+	}
+
+	share := float64(counts["Big"]) / float64(keys)
+	if share < 0.4 || share > 0.6 {
+		t.Errorf("Expected Big to own about half of the keys, got %.3f", share)
+	}
+}
+
+func TestWeight_SetWeightMovesMinimalKeys(t *testing.T) {
+	ch := New[string](50, nil)
+	for i := 0; i < 5; i++ {
+		ch.AddNode("Node" + strconv.Itoa(i))
+	}
+
+	keys := 10000
+	before := make([]string, keys)
+	for i := range before {
+		before[i] = ch.GetNode("key" + strconv.Itoa(i))
+	}
+
+	if err := ch.SetWeight("Node0", 3); err != nil {
+		t.Fatal(err)
+	}
+	for i := range before {
+		after := ch.GetNode("key" + strconv.Itoa(i))
+		if after != before[i] && after != "Node0" {
+			t.Fatalf("Key moved from %s to %s, expected moves only to Node0", before[i], after)
+		}
+	}
+
+	if err := ch.SetWeight("Node0", 1); err != nil {
+		t.Fatal(err)
+	}
+	for i := range before {
+		if after := ch.GetNode("key" + strconv.Itoa(i)); after != before[i] {
+			t.Fatalf("Expected key to return to %s after restoring the weight, got %s", before[i], after)
+		}
+	}
+}
+
+func TestWeight_RemoveWeightedNode(t *testing.T) {
+	ch := New[string](10, nil)
+	ch.AddNode("NodeA")
+	_ = ch.AddNodeWithWeight("NodeB", 5)
+	ch.RemoveNode("NodeB")
+
+	if len(ch.keys) != 10 || len(ch.hashMap) != 10 {
+		t.Errorf("Expected only NodeA virtual nodes, got %d keys", len(ch.keys))
+	}
+	if ch.totalWeight != 1 {
+		t.Errorf("Expected total weight 1, got %d", ch.totalWeight)
+	}
+}
+
+func TestWeight_BoundedCapacity(t *testing.T) {
+	ch := New[string](50, nil)
+	_ = ch.AddNodeWithWeight("Big", 3)
+	ch.AddNode("Small")
+	_ = ch.SetLoadFactor(1)
+
+	for i := 0; i < 400; i++ {
+		if _, err := ch.Acquire("key" + strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loads := ch.Loads()
+	if loads["Big"] > 300 || loads["Small"] > 100 {
+		t.Errorf("Expected loads bounded by weight, got %v", loads)
+	}
+}